@@ -0,0 +1,84 @@
+package cmd
+
+import (
+    "os"
+
+    "github.com/helviojunior/adbcat/internal/ascii"
+    "github.com/helviojunior/adbcat/pkg/readers"
+    "github.com/spf13/cobra"
+)
+
+var attachToken string
+var attachInsecure bool
+
+var attachCmd = &cobra.Command{
+    Use:   "attach <host:port>",
+    Short: "Display the logs of a remote adbcat serve",
+    Long: ascii.LogoHelp(ascii.Markdown(`
+# attach
+
+Display the logs captured by 'adbcat serve' on another host (e.g. a lab host
+with the phones plugged in) with the terminal rendering of the logcat command.
+
+The device, the package, the level and the filters are selected by the server,
+so only the wanted entries cross the network. The connection is retried when
+it is lost, continuing from the last entry seen.
+`)),
+    Example: `
+- adbcat attach lab-host:8080 --token $TOKEN -s R58M123ABC -p com.acme.app
+- adbcat attach https://lab-host:8443 -l W --filter 'tag==OkHttp && field.status>=500'
+- ADBCAT_TOKEN=xyz adbcat attach lab-host:8080 --tail 200 -o session.logcat --log-file-format logcat
+`,
+    Args: cobra.ExactArgs(1),
+    PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+        if err := rootCmd.PersistentPreRunE(cmd, args); err != nil {
+            return err
+        }
+
+        return parseLogcatOptions()
+    },
+    RunE: func(cmd *cobra.Command, args []string) error {
+        if attachToken == "" {
+            attachToken = os.Getenv("ADBCAT_TOKEN")
+        }
+
+        attacher, err := readers.NewAttacher(*opts, args[0], attachToken, attachInsecure)
+        if err != nil {
+            return err
+        }
+
+        return attacher.Run()
+    },
+}
+
+func init() {
+    rootCmd.AddCommand(attachCmd)
+
+    attachCmd.Flags().StringVar(&attachToken, "token", "", "Token of the server (default $ADBCAT_TOKEN)")
+    attachCmd.Flags().BoolVar(&attachInsecure, "insecure", false, "Do not check the certificate of the server (https://)")
+    attachCmd.Flags().IntVar(&opts.AttachTail, "tail", 0, "Entries of the history of the server displayed first")
+
+    attachCmd.Flags().StringVarP(&opts.DeviceSerial, "serial", "s", "", "Device serial number, all the devices of the server when empty")
+    attachCmd.Flags().StringVarP(&opts.PackageName, "package", "p", "", "Application package name.")
+    attachCmd.Flags().StringVarP(&opts.MinLevel, "min-level", "l", "V", "Minimum log level to be displayed (V,D,I,W,E,F) (default 'V').")
+    attachCmd.Flags().StringArrayVar(&tmpFilters, "filter", []string{}, "Display only the entries matching an expression like 'level>=W && field.status>=500' (see the README), evaluated by the server. You can repeat the flag, all the expressions must match. Use @filename to load from text file.")
+
+    attachCmd.Flags().StringArrayVar(&tmpHighlight, "highlight", []string{}, "Highlight the matches of a regex inside the messages, in the format 'style:regex' (e.g. 'bold,red:Exception'). The style is optional. You can repeat the flag. Use @filename to load rules from text file.")
+    attachCmd.Flags().StringArrayVar(&tmpTagColors, "tag-color", []string{}, "Set the color of a tag, in the format 'Tag=style' (e.g. 'OkHttp=bold,magenta'). You can repeat the flag. Use @filename to load from text file.")
+    attachCmd.Flags().StringVar(&opts.Template, "template", "", "Format of the output lines as a Go template over the entry fields, also used by the text and ANSI log files. Use @filename to load from text file.")
+    attachCmd.Flags().StringVarP(&opts.LogFile, "log-file", "o", "", "Write the displayed entries to file.")
+    attachCmd.Flags().StringVar(&opts.LogFileFormat, "log-file-format", "text", "Format of the log file: text, ansi, json (one JSON object per line) or logcat (Android Studio).")
+
+    attachCmd.Flags().BoolVar(&opts.Collapse, "collapse", false, "Fold repeated messages (same tag and message) into one line with the number of repetitions")
+    attachCmd.Flags().DurationVar(&opts.CollapseWindow, "collapse-window", 0, "With --collapse, also fold the repeated messages seen within this window (e.g. 5s), not only the consecutive ones")
+    attachCmd.Flags().BoolVar(&opts.CollapseDigits, "collapse-digits", false, "With --collapse, ignore the numbers when comparing the messages")
+
+    attachCmd.Flags().BoolVar(&opts.Wrap, "wrap", false, "Wrap the long messages at the console width, under the message column")
+    attachCmd.Flags().BoolVar(&tmpTruncate, "truncate", true, "Truncate the long messages at the console width, the default (--truncate=false is the same as --wrap)")
+    attachCmd.MarkFlagsMutuallyExclusive("wrap", "truncate")
+    attachCmd.Flags().BoolVar(&opts.PrettyJSON, "pretty-json", false, "Pretty print and color the JSON objects and arrays found in the messages")
+    attachCmd.Flags().BoolVar(&opts.PrettyXML, "pretty-xml", false, "Pretty print and color the XML found in the messages")
+
+    attachCmd.Flags().BoolVar(&opts.ShowTime, "show-time", false, "Display time")
+    attachCmd.Flags().BoolVar(&opts.ShowPid, "show-pid", false, "Display PID/TID")
+}
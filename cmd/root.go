@@ -3,6 +3,8 @@ package cmd
 import (
 	"os"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"os/signal"
     "syscall"
     "time"
@@ -10,13 +12,19 @@ import (
 
 	"github.com/helviojunior/adbcat/internal/ascii"
 	"github.com/helviojunior/adbcat/internal/tools"
+	"github.com/helviojunior/adbcat/pkg/config"
 	"github.com/helviojunior/adbcat/pkg/log"
+	"github.com/helviojunior/adbcat/pkg/models"
 	"github.com/helviojunior/adbcat/pkg/readers"
+	"github.com/fatih/color"
     "github.com/spf13/cobra"
 )
 
 var tempFolder string
 var workspacePath string
+var configFile string
+var profileName string
+var trustLocalConfig bool
 var opts = &readers.Options{}
 var rootCmd = &cobra.Command{
 	Use:   "adbcat",
@@ -27,11 +35,26 @@ var rootCmd = &cobra.Command{
 - adbcat logcat -o logcat.txt
 - adbcat logcat -p com.android.chrome
 - adbcat logcat --show-time --show-pid
+- adbcat logcat --profile network-debug
 `,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+
+		if cmd.CalledAs() != "version" {
+			if err := applyConfig(cmd); err != nil {
+				return err
+			}
+		}
+
+		if err := setupColors(); err != nil {
+			return err
+		}
 		
 	    if cmd.CalledAs() != "version" && !opts.Logging.Silence {
-			fmt.Println(ascii.Logo())
+	    	if color.NoColor {
+	    		fmt.Println(ascii.ScapeAnsi(ascii.Logo()))
+	    	}else{
+				fmt.Println(ascii.Logo())
+			}
 		}
 
 		if opts.Logging.Silence {
@@ -51,10 +74,18 @@ func Execute() {
 	
 	ascii.SetConsoleColors()
 
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
     signal.Notify(c, os.Interrupt, syscall.SIGTERM)
     go func() {
         <-c
+
+        // A running logcat stops by itself and writes what is pending,
+        // a second interrupt forces the exit
+        if runner != nil {
+            log.Warn("interrupted, shutting down...")
+            <-c
+        }
+
         ascii.ClearLine()
         fmt.Fprintf(os.Stderr, "\r\n")
         ascii.ClearLine()
@@ -97,9 +128,81 @@ func Execute() {
     fmt.Printf("\n")
 }
 
+// Loads the config files and sets the flags not given in the command line
+func applyConfig(cmd *cobra.Command) error {
+	cfg, err := config.Load(configFile, trustLocalConfig)
+	if err != nil {
+		return err
+	}
+
+	return cfg.Apply(cmd.Flags(), profileName)
+}
+
+// Sets the color mode, the color depth and the theme of the output
+func setupColors() error {
+	switch strings.ToLower(opts.ColorMode) {
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	case "auto", "":
+		// fatih/color already checks NO_COLOR and if stdout is a terminal
+	default:
+		return fmt.Errorf("invalid color mode '%s', use never, auto or always", opts.ColorMode)
+	}
+
+	depth := ascii.DetectColorDepth()
+	if color.NoColor {
+		depth = ascii.DepthNone
+		log.DisableColors()
+	} else if depth == ascii.DepthNone {
+		// Colors forced on a terminal we know nothing about
+		depth = ascii.Depth16
+	}
+	models.SetColorDepth(depth)
+
+	theme, err := loadTheme(opts.Theme)
+	if err != nil {
+		return err
+	}
+
+	return models.ApplyTheme(theme)
+}
+
+// Gets a built-in theme, a theme file or a theme from the adbcat config dir (themes/<name>.yaml)
+func loadTheme(name string) (*models.Theme, error) {
+	if name == "" {
+		return models.ThemeDark, nil
+	}
+
+	if theme, ok := models.Themes[strings.ToLower(name)]; ok {
+		return theme, nil
+	}
+
+	if tools.FileExists(name) {
+		return models.LoadThemeFile(name)
+	}
+
+	if dir := tools.ConfigDir(); dir != "" {
+		for _, ext := range []string{".yaml", ".yml"} {
+			f1 := filepath.Join(dir, "themes", name+ext)
+			if tools.FileExists(f1) {
+				return models.LoadThemeFile(f1)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("theme not found: %s", name)
+}
+
 func init() {
 	
 	rootCmd.PersistentFlags().BoolVarP(&opts.Logging.Debug, "debug-log", "D", false, "Enable debug logging")
 	rootCmd.PersistentFlags().BoolVarP(&opts.Logging.Silence, "quiet", "q", false, "Silence (almost all) logging")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file (default ~/.config/adbcat/config.yaml and ./.adbcat.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&profileName, "profile", "P", "", "Config profile to use")
+	rootCmd.PersistentFlags().BoolVar(&trustLocalConfig, "trust-local-config", false, "Let the project-local .adbcat.yaml set the options that run commands or send the logs out (--exec, --es-url, --alert-webhook...)")
+	rootCmd.PersistentFlags().StringVar(&opts.ColorMode, "color", "auto", "When to use colors: never, auto or always. NO_COLOR is respected in auto mode.")
+	rootCmd.PersistentFlags().StringVar(&opts.Theme, "theme", "dark", "Color theme: dark, light, high-contrast, a theme file or the name of a file at ~/.config/adbcat/themes.")
         
 }
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+    "fmt"
+    "os"
+    "sync"
+
+    "github.com/helviojunior/adbcat/internal/ascii"
+    "github.com/helviojunior/adbcat/pkg/adb"
+    "github.com/helviojunior/adbcat/pkg/log"
+    "github.com/helviojunior/adbcat/pkg/models"
+    "github.com/helviojunior/adbcat/pkg/readers"
+    "github.com/helviojunior/adbcat/pkg/server"
+    "github.com/spf13/cobra"
+)
+
+var serveOptions = server.ServerOptions{}
+var serveHistory int
+var serveSerials = []string{}
+var serveRunners = []*readers.LogcatRunner{}
+var serveDevices = []string{} // The serials of the runners, empty for -d and -e
+var webServer *server.Server
+var webHub *server.Hub
+
+var serveCmd = &cobra.Command{
+    Use:   "serve",
+    Short: "Serve the logs to the browser and to remote adbcat attach",
+    Long: ascii.LogoHelp(ascii.Markdown(`
+# serve
+
+Capture the logs of the devices and serve them: a live viewer in the browser,
+with colored rows, filters, search and pause, and a stream of the entries for
+'adbcat attach' on other hosts.
+
+Without -s, -d or -e the logs of all the connected devices are captured.
+Several people can watch at once, new viewers get the last entries (--history)
+first. The clients need the token (--token, $ADBCAT_TOKEN or a random one
+printed at start).
+`)),
+    Example: `
+- adbcat serve
+- adbcat serve --http :8080 -p com.acme.app
+- adbcat serve --http :8443 --tls-cert host.crt --tls-key host.key --token $TOKEN
+- adbcat serve -s emulator-5554 -s R58M123ABC --reconnect
+`,
+    PreRunE: func(cmd *cobra.Command, args []string) error {
+        serials := serveSerials
+        if len(serials) == 0 && !opts.UseDevice && !opts.UseEmulator {
+            client, err := adb.NewClient(opts.AdbBinPath, []string{})
+            if err != nil {
+                return err
+            }
+            if serials, err = client.ListDevices(); err != nil {
+                return err
+            }
+        }
+
+        // The entries of the fields are sent, so the filters of the clients can use them
+        opts.Fields = true
+
+        if len(serials) == 0 {
+            // The device of -d or -e
+            r, err := readers.NewRunner(*opts)
+            if err != nil {
+                return err
+            }
+            serveRunners = append(serveRunners, r)
+            serveDevices = append(serveDevices, "")
+        }
+        for _, serial := range serials {
+            o := *opts
+            o.DeviceSerial = serial
+            r, err := readers.NewRunner(o)
+            if err != nil {
+                return fmt.Errorf("%s: %s", serial, err)
+            }
+            serveRunners = append(serveRunners, r)
+            serveDevices = append(serveDevices, serial)
+        }
+
+        if serveOptions.Token == "" {
+            serveOptions.Token = os.Getenv("ADBCAT_TOKEN")
+        }
+        if serveOptions.Token == "" {
+            serveOptions.Token = server.NewToken()
+        }
+        serveOptions.Devices = func() []*models.DeviceInfo {
+            devices := []*models.DeviceInfo{}
+            for _, r := range serveRunners {
+                if d := r.DeviceInfo(); d != nil {
+                    devices = append(devices, d)
+                }
+            }
+            return devices
+        }
+
+        var err error
+        webHub = server.NewHub(serveHistory)
+        webServer, err = server.Listen(serveOptions, webHub)
+        if err != nil {
+            return err
+        }
+
+        return nil
+    },
+    Run: func(cmd *cobra.Command, args []string) {
+        wg := new(sync.WaitGroup)
+        for i, r := range serveRunners {
+            serial := serveDevices[i]
+            if serial == "" {
+                if d := r.DeviceInfo(); d != nil {
+                    serial = d.Serial
+                }
+            }
+            r.SetOutput(webHub.Handler(serial))
+
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                r.Run()
+            }()
+        }
+
+        log.Info("Serving the live viewer at " + webServer.URL() + " (Ctrl+C to exit)")
+        log.Info("Token of the clients (adbcat attach --token): " + serveOptions.Token)
+        if !webServer.TLS() {
+            log.Warn("The token and the entries are sent in plain text, use --tls-cert and --tls-key outside of a trusted network")
+        }
+
+        wg.Wait()
+
+        webServer.Close()
+    },
+}
+
+func init() {
+    rootCmd.AddCommand(serveCmd)
+
+    serveCmd.Flags().StringVar(&serveOptions.Addr, "http", ":8080", "Address of the web viewer and of the stream")
+    serveCmd.Flags().StringVar(&serveOptions.Token, "token", "", "Token the viewers and the clients must send (default $ADBCAT_TOKEN or a random one)")
+    serveCmd.Flags().StringVar(&serveOptions.TLSCert, "tls-cert", "", "Certificate file, to serve HTTPS (and HTTP/2)")
+    serveCmd.Flags().StringVar(&serveOptions.TLSKey, "tls-key", "", "Key file of the certificate")
+    serveCmd.Flags().IntVar(&serveHistory, "history", 5000, "Entries kept for the new viewers")
+
+    serveCmd.Flags().StringVarP(&opts.MinLevel, "min-level", "l", "V", "Minimum log level to be sent (V,D,I,W,E,F) (default 'V').")
+    serveCmd.Flags().StringVarP(&opts.PackageName, "package", "p", "", "Application package name.")
+
+    serveCmd.Flags().BoolVarP(&opts.UseDevice, "device", "d", false, "Use the first device (adb -d)")
+    serveCmd.Flags().BoolVarP(&opts.UseEmulator, "emulator", "e", false, "use the first emulator (adb -e)")
+    serveCmd.Flags().StringArrayVarP(&serveSerials, "serial", "s", []string{}, "Device serial number (adb -s), all the connected devices when empty. You can repeat the flag.")
+    serveCmd.Flags().StringVar(&opts.AdbBinPath, "adb-path", "", "Path to the ADB binary")
+    serveCmd.Flags().BoolVar(&opts.Reconnect, "reconnect", false, "Wait for the devices and continue when they are disconnected, instead of exiting")
+}
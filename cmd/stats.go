@@ -0,0 +1,63 @@
+package cmd
+
+import (
+    "fmt"
+
+    "github.com/helviojunior/adbcat/internal/ascii"
+    "github.com/helviojunior/adbcat/internal/tools"
+    "github.com/helviojunior/adbcat/pkg/readers"
+    resolver "github.com/helviojunior/gopathresolver"
+    "github.com/spf13/cobra"
+)
+
+var statsTop int
+
+var statsCmd = &cobra.Command{
+    Use:   "stats <file> [file...]",
+    Short: "Get a summary of saved logs",
+    Long: ascii.LogoHelp(ascii.Markdown(`
+# stats
+
+Get a summary of saved logs: entries by level, the top tags and PIDs by volume
+and the error/fatal entries by tag.
+
+The files may have the raw output of 'adb logcat', the text, ANSI or JSON
+log files written by adbcat, the .logcat files of Android Studio or the
+Perfetto traces with the android_log data source.
+`)),
+    Example: `
+- adbcat stats logcat.txt
+- adbcat stats --top 20 release-1.txt release-2.txt
+`,
+    Args: cobra.MinimumNArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        stats := readers.NewStats(nil)
+
+        for _, f := range args {
+            fp1, err := resolver.ResolveFullPath(f)
+            if err != nil {
+                return err
+            }
+            if !tools.FileExists(fp1) {
+                return fmt.Errorf("Invalid file path (%s): %s", f, "File not found")
+            }
+
+            unparsed, err := readers.ReadLogFile(fp1, stats.Handle)
+            if err != nil {
+                return err
+            }
+            stats.Unparsed += unparsed
+        }
+
+        stats.Finish()
+        fmt.Print(stats.Summary(statsTop, true))
+
+        return nil
+    },
+}
+
+func init() {
+    rootCmd.AddCommand(statsCmd)
+
+    statsCmd.Flags().IntVar(&statsTop, "top", 10, "Number of tags and PIDs displayed")
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+    "fmt"
+    "os"
+    "time"
+
+    "github.com/helviojunior/adbcat/internal/ascii"
+    "github.com/helviojunior/adbcat/pkg/log"
+    "github.com/helviojunior/adbcat/pkg/readers"
+    "github.com/spf13/cobra"
+)
+
+var topWindow time.Duration
+var topInterval time.Duration
+var topRows int
+var topView *readers.TopView
+
+var topCmd = &cobra.Command{
+    Use:   "top",
+    Short: "Live view of the busiest tags, PIDs and packages",
+    Long: ascii.LogoHelp(ascii.Markdown(`
+# top
+
+Live view of the busiest tags, PIDs and packages of the last seconds, with the
+lines per second, the error rate and the last message of each one.
+
+Useful to find what is flooding the log before choosing the filters.
+`)),
+    Example: `
+- adbcat top
+- adbcat top --window 30s --rows 20
+- adbcat top -p com.android.chrome -l W
+`,
+    PreRunE: func(cmd *cobra.Command, args []string) error {
+        var err error
+
+        if topInterval < 100*time.Millisecond {
+            return fmt.Errorf("invalid interval '%s', use at least 100ms", topInterval)
+        }
+
+        // Only the new lines, the old lines of the buffer would all count as arriving now
+        opts.Since = "1"
+
+        // The entries only go to the view, the terminal and the other sinks are not opened
+        topView = readers.NewTopView(topWindow)
+        runner, err = readers.NewRunnerWithOutput(*opts, topView.Handle)
+        if err != nil {
+            return err
+        }
+
+        return nil
+    },
+    Run: func(cmd *cobra.Command, args []string) {
+
+        log.Info("Starting process...")
+
+        ascii.EnterAltScreen()
+        ascii.HideCursor()
+
+        stop := make(chan bool)
+        done := make(chan bool)
+        go func() {
+            defer close(done)
+
+            ticker := time.NewTicker(topInterval)
+            defer ticker.Stop()
+
+            for {
+                width := ascii.ConsoleWidth()
+                if width <= 0 {
+                    width = 120
+                }
+
+                ascii.ClearScreen()
+                fmt.Fprintf(os.Stdout, " adbcat top - %s (Ctrl+C to exit)\n\n", time.Now().Format("15:04:05"))
+                fmt.Fprint(os.Stdout, topView.Render(topRows, width))
+
+                select {
+                case <-stop:
+                    return
+                case <-ticker.C:
+                }
+            }
+        }()
+
+        runner.Run()
+
+        close(stop)
+        <-done
+
+        ascii.ShowCursor()
+        ascii.LeaveAltScreen()
+    },
+}
+
+func init() {
+    rootCmd.AddCommand(topCmd)
+
+    topCmd.Flags().StringVarP(&opts.MinLevel, "min-level", "l", "V", "Minimum log level to be counted (V,D,I,W,E,F) (default 'V').")
+    topCmd.Flags().StringVarP(&opts.PackageName, "package", "p", "", "Application package name.")
+
+    topCmd.Flags().BoolVarP(&opts.UseDevice, "device", "d", false, "Use the first device (adb -d)")
+    topCmd.Flags().BoolVarP(&opts.UseEmulator, "emulator", "e", false, "use the first emulator (adb -e)")
+    topCmd.Flags().StringVarP(&opts.DeviceSerial, "serial", "s", "", "Sevice serial number (adb -s)")
+    topCmd.Flags().StringVar(&opts.AdbBinPath, "adb-path", "", "Path to the ADB binary")
+
+    topCmd.Flags().DurationVar(&topWindow, "window", 10*time.Second, "Time window of the counters")
+    topCmd.Flags().DurationVar(&topInterval, "interval", time.Second, "Refresh interval")
+    topCmd.Flags().IntVar(&topRows, "rows", 10, "Number of rows of each table")
+}
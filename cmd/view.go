@@ -0,0 +1,101 @@
+package cmd
+
+import (
+    "fmt"
+
+    "github.com/helviojunior/adbcat/internal/ascii"
+    "github.com/helviojunior/adbcat/internal/tools"
+    "github.com/helviojunior/adbcat/pkg/log"
+    "github.com/helviojunior/adbcat/pkg/readers"
+    resolver "github.com/helviojunior/gopathresolver"
+    "github.com/spf13/cobra"
+)
+
+var viewCmd = &cobra.Command{
+    Use:   "view <file> [file...]",
+    Short: "Display saved logs",
+    Long: ascii.LogoHelp(ascii.Markdown(`
+# view
+
+Display saved logs with the filters and the colors of the logcat command.
+
+The files may have the raw output of 'adb logcat', the text, ANSI or JSON
+log files written by adbcat, the .logcat files of Android Studio or the
+Perfetto traces with the android_log data source. With
+--log-file the entries are also written to another file, so the logs can be
+converted between the formats (e.g. to open them in Android Studio).
+`)),
+    Example: `
+- adbcat view logcat.txt
+- adbcat view -l W -p com.acme.app session.logcat
+- adbcat view logcat.txt -o session.logcat --log-file-format logcat
+- adbcat view -l E trace.perfetto-trace
+- adbcat view logcat.txt --metric 'latency_ms=latency=(\d+)ms'
+`,
+    Args: cobra.MinimumNArgs(1),
+    PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+        if err := rootCmd.PersistentPreRunE(cmd, args); err != nil {
+            return err
+        }
+
+        return parseLogcatOptions()
+    },
+    RunE: func(cmd *cobra.Command, args []string) error {
+        files := []string{}
+        for _, f := range args {
+            fp1, err := resolver.ResolveFullPath(f)
+            if err != nil {
+                return err
+            }
+            if !tools.FileExists(fp1) {
+                return fmt.Errorf("Invalid file path (%s): %s", f, "File not found")
+            }
+            files = append(files, fp1)
+        }
+
+        viewer, err := readers.NewFileViewer(*opts)
+        if err != nil {
+            return err
+        }
+
+        unparsed, err := viewer.View(files)
+        if err != nil {
+            return err
+        }
+        if unparsed > 0 {
+            log.Debug("Lines not parsed", "count", unparsed)
+        }
+
+        return nil
+    },
+}
+
+func init() {
+    rootCmd.AddCommand(viewCmd)
+
+    viewCmd.Flags().StringSliceVar(&tmpExcludeFilter, "exclude", []string{}, "Exclude all messages with specified strings. You can specify multiple values by comma-separated terms or by repeating the flag. Use @filename to load from text file.")
+    viewCmd.Flags().StringSliceVar(&tmpIncludeFilter, "include", []string{}, "Include only messages with specified strings. You can specify multiple values by comma-separated terms or by repeating the flag. Use @filename to load from text file.")
+    viewCmd.Flags().StringArrayVar(&tmpFilters, "filter", []string{}, "Display only the entries matching an expression like 'level>=W && field.status>=500' (see the README). You can repeat the flag, all the expressions must match. Use @filename to load from text file.")
+    viewCmd.Flags().BoolVar(&opts.Fields, "fields", false, "Extract the key=value pairs and the JSON fields of the messages, for the JSON log file and the templates ({{.Fields.status}})")
+    viewCmd.Flags().StringArrayVar(&tmpMetrics, "metric", []string{}, "Extract a number from the messages, in the format 'name=regex' (e.g. 'latency_ms=latency=(\\d+)ms'), and print its percentiles at the end. You can repeat the flag. Use @filename to load from text file.")
+    viewCmd.Flags().StringArrayVar(&tmpHighlight, "highlight", []string{}, "Highlight the matches of a regex inside the messages, in the format 'style:regex' (e.g. 'bold,red:Exception'). The style is optional. You can repeat the flag. Use @filename to load rules from text file.")
+    viewCmd.Flags().StringArrayVar(&tmpTagColors, "tag-color", []string{}, "Set the color of a tag, in the format 'Tag=style' (e.g. 'OkHttp=bold,magenta'). You can repeat the flag. Use @filename to load from text file.")
+    viewCmd.Flags().StringVar(&opts.Template, "template", "", "Format of the output lines as a Go template over the entry fields, also used by the text and ANSI log files. Use @filename to load from text file.")
+    viewCmd.Flags().StringVarP(&opts.LogFile, "log-file", "o", "", "Write the displayed entries to file.")
+    viewCmd.Flags().StringVar(&opts.LogFileFormat, "log-file-format", "text", "Format of the log file: text, ansi, json (one JSON object per line) or logcat (Android Studio).")
+    viewCmd.Flags().StringVarP(&opts.MinLevel, "min-level", "l", "V", "Minimum log level to be displayed (V,D,I,W,E,F) (default 'V').")
+    viewCmd.Flags().StringVarP(&opts.PackageName, "package", "p", "", "Application package name. The raw logs and the text files have the package of the processes started during the capture only.")
+
+    viewCmd.Flags().BoolVar(&opts.Collapse, "collapse", false, "Fold repeated messages (same tag and message) into one line with the number of repetitions")
+    viewCmd.Flags().DurationVar(&opts.CollapseWindow, "collapse-window", 0, "With --collapse, also fold the repeated messages seen within this window (e.g. 5s), not only the consecutive ones")
+    viewCmd.Flags().BoolVar(&opts.CollapseDigits, "collapse-digits", false, "With --collapse, ignore the numbers when comparing the messages")
+
+    viewCmd.Flags().BoolVar(&opts.Wrap, "wrap", false, "Wrap the long messages at the console width, under the message column")
+    viewCmd.Flags().BoolVar(&tmpTruncate, "truncate", true, "Truncate the long messages at the console width, the default (--truncate=false is the same as --wrap)")
+    viewCmd.MarkFlagsMutuallyExclusive("wrap", "truncate")
+    viewCmd.Flags().BoolVar(&opts.PrettyJSON, "pretty-json", false, "Pretty print and color the JSON objects and arrays found in the messages")
+    viewCmd.Flags().BoolVar(&opts.PrettyXML, "pretty-xml", false, "Pretty print and color the XML found in the messages")
+
+    viewCmd.Flags().BoolVar(&opts.ShowTime, "show-time", false, "Display time")
+    viewCmd.Flags().BoolVar(&opts.ShowPid, "show-pid", false, "Displey PID/TID")
+}
@@ -0,0 +1,112 @@
+package ascii
+
+import (
+    "os"
+    "strings"
+)
+
+const (
+    // The color depths a terminal can support
+    DepthNone      = 0
+    Depth16        = 16
+    Depth256       = 256
+    DepthTrueColor = 1 << 24
+)
+
+var (
+    // The RGB values of the 16 basic ANSI colors (xterm defaults)
+    ansi16 = [16][3]int{
+        {0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+        {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+        {127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+        {92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+    }
+
+    // The levels of each RGB component of the 256 colors cube
+    cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+)
+
+// DetectColorDepth guesses the color depth of the terminal from the environment
+func DetectColorDepth() int {
+    term := strings.ToLower(os.Getenv("TERM"))
+    colorTerm := strings.ToLower(os.Getenv("COLORTERM"))
+
+    if term == "dumb" {
+        return DepthNone
+    }
+
+    if colorTerm == "truecolor" || colorTerm == "24bit" {
+        return DepthTrueColor
+    }
+
+    // Windows Terminal, iTerm and VS Code support true color but do not always say so
+    if os.Getenv("WT_SESSION") != "" {
+        return DepthTrueColor
+    }
+    switch os.Getenv("TERM_PROGRAM") {
+    case "iTerm.app", "vscode", "WezTerm":
+        return DepthTrueColor
+    }
+
+    if strings.Contains(term, "256color") || strings.Contains(term, "truecolor") {
+        return Depth256
+    }
+
+    return Depth16
+}
+
+// RGBTo256 gets the nearest color of the 256 colors palette
+func RGBTo256(r, g, b int) int {
+    // Gray ramp (232-255) is a better match for grays than the cube
+    if r == g && g == b {
+        if r < 8 {
+            return 16
+        }
+        if r > 248 {
+            return 231
+        }
+        return 232 + (r-8)*24/247
+    }
+
+    return 16 + 36*cubeIndex(r) + 6*cubeIndex(g) + cubeIndex(b)
+}
+
+// RGBTo16 gets the nearest of the 16 basic ANSI colors (0-7 normal, 8-15 bright)
+func RGBTo16(r, g, b int) int {
+    best := 0
+    bestDist := -1
+    for i, c := range ansi16 {
+        dr, dg, db := r-c[0], g-c[1], b-c[2]
+        dist := dr*dr + dg*dg + db*db
+        if bestDist < 0 || dist < bestDist {
+            best = i
+            bestDist = dist
+        }
+    }
+
+    return best
+}
+
+// Color256ToRGB gets the RGB values of a color of the 256 colors palette
+func Color256ToRGB(n int) (int, int, int) {
+    switch {
+    case n < 16:
+        return ansi16[n][0], ansi16[n][1], ansi16[n][2]
+    case n < 232:
+        n -= 16
+        return cubeLevels[n/36], cubeLevels[(n/6)%6], cubeLevels[n%6]
+    default:
+        v := 8 + (n-232)*10
+        return v, v, v
+    }
+}
+
+func cubeIndex(v int) int {
+    if v < 48 {
+        return 0
+    }
+    if v < 115 {
+        return 1
+    }
+    return (v - 35) / 40
+}
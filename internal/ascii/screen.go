@@ -0,0 +1,22 @@
+package ascii
+
+import (
+    "fmt"
+    "os"
+)
+
+// Switches to the alternate screen, like the full screen terminal apps do.
+// Don't forget to call LeaveAltScreen at the end to get the previous screen back.
+func EnterAltScreen() {
+    fmt.Fprint(os.Stdout, "\x1b[?1049h")
+}
+
+// Goes back to the main screen, with what was there before EnterAltScreen
+func LeaveAltScreen() {
+    fmt.Fprint(os.Stdout, "\x1b[?1049l")
+}
+
+// Moves the cursor to the top left corner and clears the screen
+func ClearScreen() {
+    fmt.Fprint(os.Stdout, "\x1b[H\x1b[2J")
+}
@@ -0,0 +1,35 @@
+package ascii
+
+import (
+    "sync"
+    "sync/atomic"
+
+    "github.com/nathan-fiscaletti/consolesize-go"
+)
+
+var (
+    // The console width, cached because it is needed for every line
+    consoleWidth atomic.Int64
+    watchOnce    sync.Once
+)
+
+// Gets the console width in columns, 0 when the output is not a terminal (e.g. piped).
+// The width is cached and updated when the terminal is resized.
+func ConsoleWidth() int {
+    watchOnce.Do(func() {
+        updateConsoleWidth()
+        watchConsoleSize()
+    })
+
+    return int(consoleWidth.Load())
+}
+
+// Reads the console width again
+func updateConsoleWidth() {
+    // 3rd party because the stdlib does not provide a working solution for Windows
+    width, _ := consolesize.GetConsoleSize()
+    if width < 0 {
+        width = 0
+    }
+    consoleWidth.Store(int64(width))
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package ascii
+
+import (
+    "os"
+    "os/signal"
+    "syscall"
+)
+
+// Updates the console width when the terminal is resized
+func watchConsoleSize() {
+    c := make(chan os.Signal, 1)
+    signal.Notify(c, syscall.SIGWINCH)
+
+    go func() {
+        for range c {
+            updateConsoleWidth()
+        }
+    }()
+}
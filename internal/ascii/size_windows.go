@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package ascii
+
+import (
+    "time"
+)
+
+// Windows has no resize signal, so the console width is read again every second
+func watchConsoleSize() {
+    go func() {
+        for {
+            time.Sleep(time.Second)
+            updateConsoleWidth()
+        }
+    }()
+}
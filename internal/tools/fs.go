@@ -164,6 +164,20 @@ func TempFileName(base_path, prefix, suffix string) string {
     return filepath.Join(base_path, prefix+hex.EncodeToString(randBytes)+suffix)
 }
 
+// ConfigDir returns the adbcat configuration directory, like ~/.config/adbcat
+func ConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "adbcat")
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(homeDir, ".config", "adbcat")
+}
+
 // FileExists returns true if a path exists
 func FileExists(path string) bool {
 	_, err := os.Stat(path)
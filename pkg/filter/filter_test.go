@@ -0,0 +1,141 @@
+package filter
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+func TestMatch(t *testing.T) {
+    entry := &models.LogcatEntry{
+        Level:   "W",
+        Tag:     "OkHttp  ",
+        PID:     "1234",
+        TID:     "1240",
+        Package: "com.acme.app",
+        Message: "<-- 500 https://api.acme.com/items (320ms) status=500",
+        Fields: map[string]string{
+            "status":  "500",
+            "latency": "320ms",
+            "user":    "",
+        },
+    }
+
+    tests := []struct {
+        expr string
+        want bool
+    }{
+        // Levels by severity
+        {"level>=W", true},
+        {"level>=e", false},
+        {"level<E", true},
+        {"level==W", true},
+        {"level=w", true},
+
+        // Text ignoring the case, the tag without the padding
+        {"tag==OkHttp", true},
+        {"tag==okhttp", true},
+        {"tag!=OkHttp", false},
+        {"package==com.acme.app", true},
+        {"pid==1234 && tid==1240", true},
+
+        // Regex
+        {`msg~"status=5\d\d"`, true},
+        {`message~"(?i)TIMEOUT"`, false},
+        {`msg!~timeout`, true},
+
+        // Numbers and durations
+        {"field.status>=500", true},
+        {"field.status>500", false},
+        {"field.latency>=300ms", true},
+        {"field.latency<0.3s", false},
+
+        // Missing and empty fields
+        {"field.status", true},
+        {"field.user", false},
+        {"field.missing", false},
+        {"field.missing==1", false},
+        {"field.missing!=1", true},
+        {"field.missing!~x", true},
+
+        // Operators, && before ||
+        {"tag==Other || level>=W && field.status>=500", true},
+        {"(tag==Other || level>=W) && field.status>=500", true},
+        {"(tag==Other || level>=E) && field.status>=500", false},
+        {"tag==Other || level>=E && field.status>=500", false},
+        {"!(tag==Other)", true},
+        {"!tag==OkHttp", false},
+        {"!!field.status", true},
+
+        // Quoted values
+        {`msg~"api.acme.com/items (320ms)"`, false},
+        {`msg~'\(320ms\)'`, true},
+        {`tag=="OkHttp"`, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.expr, func(t *testing.T) {
+            f, err := Parse(tt.expr)
+            if err != nil {
+                t.Fatalf("Parse(%q): %s", tt.expr, err)
+            }
+            if got := f.Match(entry); got != tt.want {
+                t.Errorf("Match(%q) = %v, want %v", tt.expr, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseErrors(t *testing.T) {
+    tests := []struct {
+        expr string
+        err  string
+    }{
+        {"level>=", "missing the value after '>='"},
+        {"lvl==W", "unknown name 'lvl'"},
+        {"field.==1", "unknown name 'field.'"},
+        {"(level==W", "missing ')'"},
+        {"level==W)", "unexpected ')'"},
+        {"level==W &&", "unexpected end of the expression"},
+        {`msg~"unclosed`, "missing closing quote"},
+        {"msg~(", "missing the value after '~'"},
+        {`msg~"("`, "invalid regex '('"},
+        {"level==W tag==A", "unexpected 'tag'"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.expr, func(t *testing.T) {
+            _, err := Parse(tt.expr)
+            if err == nil {
+                t.Fatalf("Parse(%q) did not fail", tt.expr)
+            }
+            if !strings.Contains(err.Error(), tt.err) {
+                t.Errorf("Parse(%q) = %q, want %q", tt.expr, err, tt.err)
+            }
+        })
+    }
+}
+
+func TestUsesFields(t *testing.T) {
+    tests := []struct {
+        expr string
+        want bool
+    }{
+        {"level>=W", false},
+        {`msg~"status=500"`, false},
+        {"level>=W && field.status>=500", true},
+        {"!field.user", true},
+        {"field.Status==1", true},
+    }
+
+    for _, tt := range tests {
+        f, err := Parse(tt.expr)
+        if err != nil {
+            t.Fatalf("Parse(%q): %s", tt.expr, err)
+        }
+        if got := f.UsesFields(); got != tt.want {
+            t.Errorf("UsesFields(%q) = %v, want %v", tt.expr, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,218 @@
+package filter
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+const (
+    tokenWord   = iota // A name or a bare value
+    tokenString        // A quoted value
+    tokenOp            // A comparison operator
+    tokenAnd
+    tokenOr
+    tokenNot
+    tokenOpen
+    tokenClose
+)
+
+type token struct {
+    kind int
+    text string
+}
+
+type parser struct {
+    tokens []token
+    pos    int
+    fields bool
+}
+
+// Splits the expression in tokens
+func tokenize(text string) ([]token, error) {
+    tokens := []token{}
+    i := 0
+    for i < len(text) {
+        c := text[i]
+        two := ""
+        if i+1 < len(text) {
+            two = text[i : i+2]
+        }
+
+        switch {
+        case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+            i++
+        case two == "&&":
+            tokens = append(tokens, token{tokenAnd, two})
+            i += 2
+        case two == "||":
+            tokens = append(tokens, token{tokenOr, two})
+            i += 2
+        case two == "==" || two == "!=" || two == ">=" || two == "<=" || two == "!~":
+            tokens = append(tokens, token{tokenOp, two})
+            i += 2
+        case c == '>' || c == '<' || c == '~':
+            tokens = append(tokens, token{tokenOp, string(c)})
+            i++
+        case c == '=':
+            // A single = works as ==
+            tokens = append(tokens, token{tokenOp, "=="})
+            i++
+        case c == '!':
+            tokens = append(tokens, token{tokenNot, "!"})
+            i++
+        case c == '(':
+            tokens = append(tokens, token{tokenOpen, "("})
+            i++
+        case c == ')':
+            tokens = append(tokens, token{tokenClose, ")"})
+            i++
+        case c == '"' || c == '\'':
+            s, n, err := readQuoted(text[i:])
+            if err != nil {
+                return nil, err
+            }
+            tokens = append(tokens, token{tokenString, s})
+            i += n
+        default:
+            j := i
+            for j < len(text) && !strings.ContainsRune(" \t\r\n()&|=!<>~\"'", rune(text[j])) {
+                j++
+            }
+            tokens = append(tokens, token{tokenWord, text[i:j]})
+            i = j
+        }
+    }
+
+    return tokens, nil
+}
+
+// Reads a quoted value, the quote may be escaped with a backslash
+func readQuoted(text string) (string, int, error) {
+    quote := text[0]
+    sb := strings.Builder{}
+    for i := 1; i < len(text); i++ {
+        switch {
+        case text[i] == '\\' && i+1 < len(text) && (text[i+1] == quote || text[i+1] == '\\'):
+            sb.WriteByte(text[i+1])
+            i++
+        case text[i] == quote:
+            return sb.String(), i + 1, nil
+        default:
+            sb.WriteByte(text[i])
+        }
+    }
+
+    return "", 0, fmt.Errorf("missing closing quote")
+}
+
+func (p *parser) peek() *token {
+    if p.pos < len(p.tokens) {
+        return &p.tokens[p.pos]
+    }
+    return nil
+}
+
+func (p *parser) next() *token {
+    t := p.peek()
+    if t != nil {
+        p.pos++
+    }
+    return t
+}
+
+func (p *parser) parseOr() (node, error) {
+    left, err := p.parseAnd()
+    if err != nil {
+        return nil, err
+    }
+
+    for t := p.peek(); t != nil && t.kind == tokenOr; t = p.peek() {
+        p.next()
+        right, err := p.parseAnd()
+        if err != nil {
+            return nil, err
+        }
+        left = orNode{left, right}
+    }
+
+    return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+    left, err := p.parseUnary()
+    if err != nil {
+        return nil, err
+    }
+
+    for t := p.peek(); t != nil && t.kind == tokenAnd; t = p.peek() {
+        p.next()
+        right, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        left = andNode{left, right}
+    }
+
+    return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+    t := p.next()
+    if t == nil {
+        return nil, fmt.Errorf("unexpected end of the expression")
+    }
+
+    switch t.kind {
+    case tokenNot:
+        expr, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        return notNode{expr}, nil
+
+    case tokenOpen:
+        expr, err := p.parseOr()
+        if err != nil {
+            return nil, err
+        }
+        if c := p.next(); c == nil || c.kind != tokenClose {
+            return nil, fmt.Errorf("missing ')'")
+        }
+        return expr, nil
+
+    case tokenWord:
+        name := strings.ToLower(t.text)
+        if strings.HasPrefix(name, "field.") {
+            // The field names keep their case
+            name = "field." + t.text[len("field."):]
+            p.fields = true
+        }
+        if !validName(name) {
+            return nil, fmt.Errorf("unknown name '%s', use level, tag, pid, tid, package, msg or field.<name>", t.text)
+        }
+
+        op := p.peek()
+        if op == nil || op.kind != tokenOp {
+            return existsNode{name: name}, nil
+        }
+        p.next()
+
+        v := p.next()
+        if v == nil || (v.kind != tokenWord && v.kind != tokenString) {
+            return nil, fmt.Errorf("missing the value after '%s'", op.text)
+        }
+
+        n := compareNode{name: name, op: op.text, value: v.text}
+        if n.op == "~" || n.op == "!~" {
+            re, err := regexp.Compile(v.text)
+            if err != nil {
+                return nil, fmt.Errorf("invalid regex '%s': %s", v.text, err)
+            }
+            n.re = re
+        }
+        return n, nil
+    }
+
+    return nil, fmt.Errorf("unexpected '%s'", t.text)
+}
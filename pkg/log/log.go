@@ -6,6 +6,7 @@ import (
 
     "github.com/charmbracelet/lipgloss"
     "github.com/charmbracelet/log"
+    "github.com/muesli/termenv"
 )
 
 // LLogger is a charmbracelet logger type redefinition
@@ -37,6 +38,11 @@ func EnableSilence() {
     Logger.SetLevel(log.FatalLevel + 100)
 }
 
+// DisableColors makes the logger write plain text
+func DisableColors() {
+    Logger.SetColorProfile(termenv.Ascii)
+}
+
 // Debug logs debug messages
 func Debug(msg string, keyvals ...interface{}) {
     Logger.Helper()
@@ -0,0 +1,362 @@
+package metrics
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "math"
+    "net"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// Registry holds the metrics and writes them in the Prometheus text format
+type Registry struct {
+    mutex   sync.Mutex
+    metrics []metric
+    names   map[string]bool // The names of the samples of the metrics
+}
+
+// A metric family of the registry
+type metric interface {
+    write(w *bufio.Writer)
+    // The names of the samples, e.g. the _bucket, _sum and _count of a histogram
+    sampleNames() []string
+}
+
+// Sample is one value of a metric computed at scrape time, the labels are in the
+// order of the label names of the metric
+type Sample struct {
+    Labels []string
+    Value  float64
+}
+
+// CounterVec is a counter with labels
+type CounterVec struct {
+    name   string
+    help   string
+    labels []string
+
+    mutex  sync.Mutex
+    series map[string]*Sample
+}
+
+// HistogramVec is a histogram with labels
+type HistogramVec struct {
+    name    string
+    help    string
+    labels  []string
+    buckets []float64
+
+    mutex  sync.Mutex
+    series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+    labels []string
+    counts []uint64 // By bucket, not cumulative
+    count  uint64
+    sum    float64
+}
+
+// A metric whose samples are read when it is scraped
+type funcMetric struct {
+    name   string
+    help   string
+    kind   string
+    labels []string
+    fn     func() []Sample
+}
+
+func NewRegistry() *Registry {
+    return &Registry{
+        metrics: []metric{},
+        names:   map[string]bool{},
+    }
+}
+
+// Registers a counter with the label names
+func (r *Registry) Counter(name string, help string, labels ...string) *CounterVec {
+    c := &CounterVec{
+        name:   name,
+        help:   help,
+        labels: labels,
+        series: map[string]*Sample{},
+    }
+    r.add(c)
+    return c
+}
+
+// Registers a histogram with the upper bounds of the buckets (sorted) and the label names
+func (r *Registry) Histogram(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+    h := &HistogramVec{
+        name:    name,
+        help:    help,
+        labels:  labels,
+        buckets: buckets,
+        series:  map[string]*histogramSeries{},
+    }
+    r.add(h)
+    return h
+}
+
+// Registers a counter whose samples are read by fn when the metrics are scraped
+func (r *Registry) CounterFunc(name string, help string, labels []string, fn func() []Sample) {
+    r.add(&funcMetric{name: name, help: help, kind: "counter", labels: labels, fn: fn})
+}
+
+// Registers a gauge whose samples are read by fn when the metrics are scraped
+func (r *Registry) GaugeFunc(name string, help string, labels []string, fn func() []Sample) {
+    r.add(&funcMetric{name: name, help: help, kind: "gauge", labels: labels, fn: fn})
+}
+
+func (r *Registry) add(m metric) {
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+
+    r.metrics = append(r.metrics, m)
+    for _, n := range m.sampleNames() {
+        r.names[n] = true
+    }
+}
+
+// Checks if a histogram with this name would clash with the metrics of the registry
+func (r *Registry) HistogramClashes(name string) bool {
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+
+    for _, n := range histogramNames(name) {
+        if r.names[n] {
+            return true
+        }
+    }
+    return false
+}
+
+func histogramNames(name string) []string {
+    return []string{name, name + "_bucket", name + "_sum", name + "_count"}
+}
+
+func (c *CounterVec) sampleNames() []string {
+    return []string{c.name}
+}
+
+func (h *HistogramVec) sampleNames() []string {
+    return histogramNames(h.name)
+}
+
+func (f *funcMetric) sampleNames() []string {
+    return []string{f.name}
+}
+
+// Writes all the metrics in the Prometheus text format
+func (r *Registry) Write(w io.Writer) error {
+    r.mutex.Lock()
+    metrics := append([]metric{}, r.metrics...)
+    r.mutex.Unlock()
+
+    bw := bufio.NewWriter(w)
+    for _, m := range metrics {
+        m.write(bw)
+    }
+    return bw.Flush()
+}
+
+// Gets a handler serving the metrics
+func (r *Registry) Handler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+        w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+        r.Write(w)
+    })
+}
+
+// Serves the metrics at http://addr/metrics. The address is checked before returning,
+// the server runs until it is closed.
+func Serve(addr string, registry *Registry) (*http.Server, error) {
+    ln, err := net.Listen("tcp", addr)
+    if err != nil {
+        return nil, fmt.Errorf("invalid metrics address '%s': %s", addr, err)
+    }
+
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", registry.Handler())
+
+    srv := &http.Server{Handler: mux}
+    go srv.Serve(ln)
+
+    return srv, nil
+}
+
+// Adds 1 to the series of the label values
+func (c *CounterVec) Inc(values ...string) {
+    c.Add(1, values...)
+}
+
+// Adds v to the series of the label values
+func (c *CounterVec) Add(v float64, values ...string) {
+    key := strings.Join(values, "\xff")
+
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    s, ok := c.series[key]
+    if !ok {
+        s = &Sample{Labels: append([]string{}, values...)}
+        c.series[key] = s
+    }
+    s.Value += v
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+    c.mutex.Lock()
+    samples := make([]Sample, 0, len(c.series))
+    for _, s := range c.series {
+        samples = append(samples, *s)
+    }
+    c.mutex.Unlock()
+
+    writeFamily(w, c.name, c.help, "counter", c.labels, samples)
+}
+
+// Adds an observed value to the series of the label values
+func (h *HistogramVec) Observe(v float64, values ...string) {
+    key := strings.Join(values, "\xff")
+
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    s, ok := h.series[key]
+    if !ok {
+        s = &histogramSeries{
+            labels: append([]string{}, values...),
+            counts: make([]uint64, len(h.buckets)),
+        }
+        h.series[key] = s
+    }
+
+    for i, le := range h.buckets {
+        if v <= le {
+            s.counts[i]++
+            break
+        }
+    }
+    s.count++
+    s.sum += v
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+    fmt.Fprintf(w, "# HELP %s %s\n", h.name, escapeHelp(h.help))
+    fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    keys := make([]string, 0, len(h.series))
+    for k := range h.series {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+
+    for _, k := range keys {
+        s := h.series[k]
+
+        cumulative := uint64(0)
+        for i, le := range h.buckets {
+            cumulative += s.counts[i]
+            fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, s.labels, "le", formatValue(le)), cumulative)
+        }
+        fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, s.labels, "le", "+Inf"), s.count)
+        fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, s.labels, "", ""), formatValue(s.sum))
+        fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, s.labels, "", ""), s.count)
+    }
+}
+
+func (f *funcMetric) write(w *bufio.Writer) {
+    writeFamily(w, f.name, f.help, f.kind, f.labels, f.fn())
+}
+
+// Writes the HELP and TYPE lines and the samples, sorted by labels
+func writeFamily(w *bufio.Writer, name string, help string, kind string, labels []string, samples []Sample) {
+    fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+    fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+
+    sort.Slice(samples, func(i, j int) bool {
+        return strings.Join(samples[i].Labels, "\xff") < strings.Join(samples[j].Labels, "\xff")
+    })
+    for _, s := range samples {
+        fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(labels, s.Labels, "", ""), formatValue(s.Value))
+    }
+}
+
+// Formats the labels like {a="1",b="2"}, extra is an additional label (e.g. le of the histograms)
+func formatLabels(names []string, values []string, extraName string, extraValue string) string {
+    parts := []string{}
+    for i, n := range names {
+        v := ""
+        if i < len(values) {
+            v = values[i]
+        }
+        parts = append(parts, n+`="`+escapeLabel(v)+`"`)
+    }
+    if extraName != "" {
+        parts = append(parts, extraName+`="`+escapeLabel(extraValue)+`"`)
+    }
+
+    if len(parts) == 0 {
+        return ""
+    }
+    return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(v float64) string {
+    switch {
+    case math.IsInf(v, 1):
+        return "+Inf"
+    case math.IsInf(v, -1):
+        return "-Inf"
+    case math.IsNaN(v):
+        return "NaN"
+    }
+    return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeLabel(s string) string {
+    return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+    return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// ValueCap limits the distinct values of a label, so a label like the tag does not
+// create too many series. The values after the first max ones are replaced by "other".
+type ValueCap struct {
+    max   int
+    mutex sync.Mutex
+    seen  map[string]bool
+}
+
+func NewValueCap(max int) *ValueCap {
+    return &ValueCap{
+        max:  max,
+        seen: map[string]bool{},
+    }
+}
+
+// Gets the value, or "other" when the cap was reached
+func (vc *ValueCap) Value(v string) string {
+    vc.mutex.Lock()
+    defer vc.mutex.Unlock()
+
+    if vc.seen[v] {
+        return v
+    }
+    if vc.max > 0 && len(vc.seen) >= vc.max {
+        return "other"
+    }
+    vc.seen[v] = true
+    return v
+}
@@ -0,0 +1,29 @@
+package models
+
+// DeviceInfo describes the device the logs came from
+type DeviceInfo struct {
+    Serial       string `json:"serial"`
+    Manufacturer string `json:"manufacturer,omitempty"`
+    Model        string `json:"model,omitempty"`
+    Release      string `json:"release,omitempty"` // The Android version, like 14
+    SDK          int    `json:"sdk,omitempty"`
+    BuildID      string `json:"build_id,omitempty"`   // Like UQ1A.240205.004
+    BuildType    string `json:"build_type,omitempty"` // user, userdebug or eng
+    Fingerprint  string `json:"fingerprint,omitempty"`
+    Emulator     bool   `json:"emulator,omitempty"`
+}
+
+// Gets a name for the device like "Google Pixel 7 (Android 14)"
+func (d DeviceInfo) Name() string {
+    name := d.Model
+    if d.Manufacturer != "" && name != "" {
+        name = d.Manufacturer + " " + name
+    }
+    if name == "" {
+        name = d.Serial
+    }
+    if d.Release != "" {
+        name += " (Android " + d.Release + ")"
+    }
+    return name
+}
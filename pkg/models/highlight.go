@@ -0,0 +1,148 @@
+package models
+
+import (
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/fatih/color"
+)
+
+const (
+    // The style used by rules that do not name one, and by the --include terms,
+    // unless the theme sets another one
+    DefaultHighlightStyle = "bold,black,on-yellow"
+)
+
+// A rule that colors every match of Pattern inside the messages
+type HighlightRule struct {
+    Pattern *regexp.Regexp
+    Color   *color.Color
+}
+
+// A colored slice of a message, from start to end (byte offsets)
+type span struct {
+    start int
+    end   int
+    color *color.Color
+}
+
+var (
+    // The registered highlight rules, in order of priority
+    highlightRules = []*HighlightRule{}
+)
+
+// Creates a highlight rule from the pattern and the style
+func NewHighlightRule(pattern string, style string) (*HighlightRule, error) {
+    re, err := regexp.Compile(pattern)
+    if err != nil {
+        return nil, fmt.Errorf("invalid highlight pattern '%s': %s", pattern, err)
+    }
+
+    if style == "" {
+        style = highlightStyle
+    }
+
+    c, err := ParseStyle(style)
+    if err != nil {
+        return nil, err
+    }
+
+    return &HighlightRule{
+        Pattern: re,
+        Color:   c,
+    }, nil
+}
+
+// Parses a rule in the format "style:regex" like "bold,red:Exception".
+//
+// When the text before the first ':' is not a valid style the whole
+// rule is taken as the regex and the default style is used.
+func ParseHighlightRule(rule string) (*HighlightRule, error) {
+    if p := strings.SplitN(rule, ":", 2); len(p) == 2 && p[1] != "" {
+        if _, err := ParseStyle(p[0]); err == nil {
+            return NewHighlightRule(p[1], p[0])
+        }
+    }
+
+    return NewHighlightRule(rule, "")
+}
+
+// Registers a rule to be used by the colored output
+func AddHighlightRule(rule *HighlightRule) {
+    highlightRules = append(highlightRules, rule)
+}
+
+// Registers a rule that highlights the literal term ignoring case
+func AddHighlightTerm(term string) error {
+    rule, err := NewHighlightRule("(?i)"+regexp.QuoteMeta(term), "")
+    if err != nil {
+        return err
+    }
+
+    AddHighlightRule(rule)
+    return nil
+}
+
+// Removes all registered highlight rules
+func ClearHighlightRules() {
+    highlightRules = []*HighlightRule{}
+}
+
+// Gets the spans of the text matched by the highlight rules.
+// Overlapping matches are dropped, the first registered rule wins.
+func highlightSpans(text string) []span {
+    spans := []span{}
+    for _, rule := range highlightRules {
+        for _, m := range rule.Pattern.FindAllStringIndex(text, -1) {
+            if m[1] <= m[0] {
+                continue
+            }
+            overlaps := false
+            for _, s := range spans {
+                if m[0] < s.end && s.start < m[1] {
+                    overlaps = true
+                    break
+                }
+            }
+            if !overlaps {
+                spans = append(spans, span{start: m[0], end: m[1], color: rule.Color})
+            }
+        }
+    }
+
+    sort.Slice(spans, func(i, j int) bool {
+        return spans[i].start < spans[j].start
+    })
+
+    return spans
+}
+
+// Colors the text with the base color and the spans on top of it.
+// The spans must be sorted and must not overlap.
+func paint(text string, base *color.Color, spans []span) string {
+    if len(spans) == 0 {
+        return base.Sprint(text)
+    }
+
+    out := ""
+    pos := 0
+    for _, s := range spans {
+        if s.start > pos {
+            out += base.Sprint(text[pos:s.start])
+        }
+        out += s.color.Sprint(text[s.start:s.end])
+        pos = s.end
+    }
+    if pos < len(text) {
+        out += base.Sprint(text[pos:])
+    }
+
+    return out
+}
+
+// Colors a message line with the base color and the highlight rules
+func highlight(text string, base *color.Color) string {
+    return paint(text, base, highlightSpans(text))
+}
@@ -1,13 +1,14 @@
 package models
 
 import (
+    "encoding/json"
     "fmt"
     "os"
     "strings"
+    "time"
 
     "github.com/helviojunior/adbcat/internal/ascii"
     "github.com/fatih/color"
-    "github.com/nathan-fiscaletti/consolesize-go"
 )
 
 const (
@@ -25,25 +26,14 @@ const (
 )
 
 var (
-    // The colors for the log levels
-    colorLevel = []*color.Color{
-        color.New(color.BgBlack, color.FgWhite),  // Verbose
-        color.New(color.BgBlack, color.FgCyan),   // Debug
-        color.New(color.BgBlack, color.FgGreen),  // Info
-        color.New(color.BgBlack, color.FgYellow), // Warning
-        color.New(color.BgBlack, color.FgRed),    // Error
-        color.New(color.BgBlack, color.FgRed),    // Fatal
-    }
+    // The colors for the log levels, set by the theme
+    colorLevel = []*color.Color{}
 
-    // A slice of colors for the tags that gets rotated when a new tag is encountered
-    colorTags = []*color.Color{
-        color.New(color.FgWhite).AddBgRGB(60,60,60),  // Verbose
-        color.New(color.FgCyan).AddBgRGB(60,60,60),   // Debug
-        color.New(color.FgGreen).AddBgRGB(60,60,60),  // Info
-        color.New(color.FgYellow).AddBgRGB(60,60,60), // Warning
-        color.New(color.FgRed).AddBgRGB(60,60,60),    // Error
-        color.New(color.FgRed).AddBgRGB(60,60,60),    // Fatal
-    }
+    // The colors for the level badge, set by the theme
+    colorLevelBadge = []*color.Color{}
+
+    // Wrap the long messages instead of truncating them (see --wrap)
+    wrapMessages = false
 
     LevelMap = map[string]int{
         "V": LevelVerbose,
@@ -64,6 +54,14 @@ type LogcatEntry struct {
     PID         string       `json:"pid"`
     TID         string       `json:"tid"`
     Message     string       `json:"message"`
+    Package     string       `json:"package,omitempty"`
+
+    // The key=value pairs and the JSON fields found in the message (see --fields)
+    Fields      map[string]string `json:"fields,omitempty"`
+
+    // How many times the message was repeated (see --collapse) and over how many seconds
+    Count       int          `json:"count,omitempty"`
+    CountSpan   float64      `json:"count_span,omitempty"`
 }
 
 
@@ -73,6 +71,13 @@ func (entry LogcatEntry) ToAnsiString() string {
 
 func (entry LogcatEntry) FormatAnsiString(showTime bool, showPid bool, cutMessage bool) string {
 
+    // The template replaces the fixed columns
+    if lineTemplate != nil {
+        if text, err := entry.formatTemplate(true); err == nil {
+            return text
+        }
+    }
+
     time := ""
     if showTime {
         time = formatTime(entry.Time)
@@ -85,24 +90,46 @@ func (entry LogcatEntry) FormatAnsiString(showTime bool, showPid bool, cutMessag
 
     // Color the level based on the log level
     c1 := colorLevel[LevelMap[entry.Level]]
-    coloredLevel := colorTags[LevelMap[entry.Level]].Sprintf(" %s ", entry.Level)
-    coloredName := c1.Sprintf("%s", name)
-
-    prefix := "\033[0m\033[1;90m" + time + pid + "\033[0m\033[1;90m\033[0m" + coloredName
-    prefixLen := len(ascii.ScapeAnsi(prefix))
-    prefixLen2 := len(ascii.ScapeAnsi(prefix+coloredLevel))
+    coloredLevel := colorLevelBadge[LevelMap[entry.Level]].Sprintf(" %s ", entry.Level)
+    coloredName := TagColor(entry.Tag).Sprintf("%s", name)
+
+    prefix := colorPrefix.Sprint(time + pid) + coloredName
+    prefixLen := displayWidth(ascii.ScapeAnsi(prefix))
+    prefixLen2 := displayWidth(ascii.ScapeAnsi(prefix+coloredLevel))
+    suffix := entry.RepeatSuffix()
+    message, kinds := prettyMessage(entry.Message)
+    lines := strings.Split(message, "\n")
     coloredMsg := ""
-    for i, line := range strings.Split(entry.Message, "\n") {
-        msg := ""
-        if cutMessage {
-            msg = formatMsg(line, prefixLen2)
-        }else{
-            msg = line
+    for i, line := range lines {
+        last := i == len(lines)-1
+        kind := lineText
+        if kinds != nil {
+            kind = kinds[i]
         }
-        if i == 0 {
-            coloredMsg += prefix + coloredLevel + c1.Sprint(msg)
-        }else{
-            coloredMsg += fmt.Sprintf("\n%*s%s%s", prefixLen, "", coloredLevel, c1.Sprint(msg))
+        reserve := prefixLen2
+        if last {
+            reserve += displayWidth(suffix)
+        }
+
+        // Long lines are wrapped under the message column or truncated
+        parts := []string{line}
+        if cutMessage && wrapMessages {
+            parts = wrapMsg(line, reserve)
+        }
+
+        for j, part := range parts {
+            msg := part
+            if cutMessage {
+                msg = formatMsg(part, reserve)
+            }
+            if last && j == len(parts)-1 {
+                msg += colorPrefix.Sprint(suffix)
+            }
+            if i == 0 && j == 0 {
+                coloredMsg += prefix + coloredLevel + highlightLine(msg, c1, kind)
+            }else{
+                coloredMsg += fmt.Sprintf("\n%*s%s%s", prefixLen, "", coloredLevel, highlightLine(msg, c1, kind))
+            }
         }
     }
 
@@ -110,17 +137,25 @@ func (entry LogcatEntry) FormatAnsiString(showTime bool, showPid bool, cutMessag
 }
 
 func (entry LogcatEntry) ToString() string {
-    
+
+    // The template replaces the fixed columns
+    if lineTemplate != nil {
+        if text, err := entry.formatTemplate(false); err == nil {
+            return text
+        }
+    }
+
     time := formatTime(entry.Time)
     pid := entry.GetFormattedPidTid()
-    name := fmt.Sprintf("%*s", MaxLenTag, entry.Tag) 
+    name := padLeftWidth(entry.Tag, MaxLenTag)
 
     level := fmt.Sprintf(" %s ", entry.Level)
 
     prefix := ascii.ScapeAnsi(time+pid+name)
-    prefixLen := len(prefix)
+    prefixLen := displayWidth(prefix)
     msg := ""
-    for i, line := range strings.Split(entry.Message, "\n") {
+    message, _ := prettyMessage(entry.Message)
+    for i, line := range strings.Split(message+entry.RepeatSuffix(), "\n") {
         if i == 0 {
             msg += prefix + level + ascii.ScapeAnsi(line)
         }else{
@@ -131,6 +166,36 @@ func (entry LogcatEntry) ToString() string {
     return msg
 }
 
+// Gets the entry as a JSON object
+func (entry LogcatEntry) ToJson() string {
+    data, err := json.Marshal(entry)
+    if err != nil {
+        return "{}"
+    }
+
+    return string(data)
+}
+
+// Gets the suffix of a collapsed entry like " (repeated ×12 over 3.2s)"
+func (entry LogcatEntry) RepeatSuffix() string {
+    if entry.Count <= 1 {
+        return ""
+    }
+
+    return fmt.Sprintf(" (repeated ×%d over %.1fs)", entry.Count, entry.CountSpan)
+}
+
+// Gets the time of the entry. The logcat lines have no year, so the current one is used.
+func (entry LogcatEntry) Timestamp() (time.Time, error) {
+    date := entry.Date
+    if date == "" {
+        date = time.Now().Format("01-02")
+    }
+
+    return time.ParseInLocation("2006-01-02 15:04:05.000",
+        fmt.Sprintf("%d-%s %s", time.Now().Year(), date, entry.Time), time.Local)
+}
+
 // Prints a logcat line with colors
 func (entry LogcatEntry) Print() {
     fmt.Fprintln(color.Output, entry.ToAnsiString())
@@ -164,26 +229,23 @@ func (entry LogcatEntry) ToAnsiFile(fh *os.File) (err error) {
     return nil
 }
 
-func (entry LogcatEntry) GetFormattedPidTid() string {
-    pid := entry.PID
-
-    if len(pid) > MaxLenPid {
-        return pid[:MaxLenPid]
+// Writes a logcat line to a file as a JSON object
+func (entry LogcatEntry) ToJsonFile(fh *os.File) (err error) {
+    if fh == nil {
+        return nil
     }
 
-    for len(pid) < MaxLenPid {
-        pid = " " + pid
+    _, err = fmt.Fprintf(fh, "%s\n", entry.ToJson())
+    if err != nil {
+        return err
     }
 
-    tid := entry.TID
-
-    if len(tid) > MaxLenPid {
-        return tid[:MaxLenPid]
-    }
+    return nil
+}
 
-    for len(tid) < MaxLenPid {
-        tid += " "
-    }
+func (entry LogcatEntry) GetFormattedPidTid() string {
+    pid := padLeftWidth(truncateWidth(entry.PID, MaxLenPid), MaxLenPid)
+    tid := padRightWidth(truncateWidth(entry.TID, MaxLenPid), MaxLenPid)
 
     return fmt.Sprintf("%s-%s ", pid, tid)
 }
@@ -196,16 +258,12 @@ func formatTime(time string) string {
     }
 
     // Trim the tag if it's too long
-    if len(time) > MaxLenTime {
-        return time[:MaxLenTime-1] + " "
+    if displayWidth(time) > MaxLenTime {
+        return truncateWidth(time, MaxLenTime-1) + " "
     }
 
     // Add spaces to fill the rest of the line
-    for len(time) < MaxLenTime {
-        time += " "
-    }
-
-    return time
+    return padRightWidth(time, MaxLenTime)
 }
 
 // Formats the tag to be colored and have a fixed length
@@ -216,17 +274,19 @@ func formatTag(tag string) string {
     }
 
     // Trim the tag if it's too long
-    if len(tag) > MaxLenTag {
-        tag = tag[:MaxLenTag-3] + "..."
-    }
+    tag = ellipsisWidth(tag, MaxLenTag, "...")
 
-    return fmt.Sprintf("%*s", MaxLenTag, tag)
+    return padLeftWidth(tag, MaxLenTag)
 }
 
 // Formats the message to have a fixed length
 func formatMsg(msg string, prefixSize int) string {
-    // Get the console width (3rd party because the stdlib does not provide a working solution for Windows)
-    width, _ := consolesize.GetConsoleSize()
+    width := ascii.ConsoleWidth()
+
+    // Not a terminal (e.g. the output is piped), keep the message as is
+    if width <= 0 {
+        return msg
+    }
 
     // Calculate the maximum width for the message
     //maxWidthMsg := width - MaxLenTime - MaxLenPid - MaxLenPid - MaxLenTag - 5 // 5 = 3 spaces, 1 char for level and 1 char for -
@@ -238,18 +298,33 @@ func formatMsg(msg string, prefixSize int) string {
     }
 
     // Trim the message if it's too long
-    if len(msg) > maxWidthMsg {
-        return msg[:maxWidthMsg-4] + "... "
+    if displayWidth(msg) > maxWidthMsg {
+        return padRightWidth(ellipsisWidth(msg, maxWidthMsg-1, "..."), maxWidthMsg)
     }
 
     // Add spaces to fill the rest of the line
-    for len(msg) < maxWidthMsg {
-        msg += " "
+    return padRightWidth(msg, maxWidthMsg)
+}
+
+// Splits the message in lines that fit the console, breaking at the spaces when possible
+func wrapMsg(msg string, prefixSize int) []string {
+    width := ascii.ConsoleWidth()
+
+    // 1 char for the space before the message and 1 for the last column
+    maxWidthMsg := width - prefixSize - 2
+
+    // Not a terminal or too narrow to wrap
+    if width <= 0 || maxWidthMsg < 10 {
+        return []string{msg}
     }
 
-    return msg
+    return splitWidth(msg, maxWidthMsg)
 }
 
+// Sets if the long messages are wrapped at the console width instead of truncated
+func SetWrap(wrap bool) {
+    wrapMessages = wrap
+}
 
 type NoDataError struct {
 	Message string
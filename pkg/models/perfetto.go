@@ -0,0 +1,358 @@
+package models
+
+import (
+    "bufio"
+    "bytes"
+    "compress/zlib"
+    "encoding/binary"
+    "fmt"
+    "io"
+    "math"
+    "sort"
+    "strings"
+    "time"
+)
+
+// The Perfetto traces are a protobuf Trace message, a sequence of TracePacket:
+//
+//    Trace        { repeated TracePacket packet = 1; }
+//    TracePacket  { ProcessTree process_tree = 2; AndroidLogPacket android_log = 39;
+//                   bytes compressed_packets = 50; (a zlib compressed Trace) }
+//    AndroidLogPacket { repeated LogEvent events = 1; }
+//    LogEvent     { int32 pid = 2; int32 tid = 3; uint64 timestamp = 5; string tag = 6;
+//                   AndroidLogPriority prio = 7; string message = 8; repeated Arg args = 9; }
+//    Arg          { string name = 1; int64 int_value = 2; float float_value = 3; string string_value = 4; }
+//    ProcessTree  { repeated Process processes = 1; }
+//    Process      { int32 pid = 1; repeated string cmdline = 3; }
+//
+// Only the fields above are decoded, everything else is skipped.
+
+const (
+    wireVarint  = 0
+    wireFixed64 = 1
+    wireBytes   = 2
+    wireFixed32 = 5
+
+    // The largest packet read, the packets of the traces are usually a few KB
+    // (the compressed ones up to some hundred KB)
+    perfettoMaxPacket = 64 * 1024 * 1024
+)
+
+var (
+    // The logcat levels by AndroidLogPriority
+    perfettoLevels = map[uint64]string{
+        2: "V",
+        3: "D",
+        4: "I",
+        5: "W",
+        6: "E",
+        7: "F",
+    }
+)
+
+// A log event and its timestamp (ns), the entries are sorted by it
+type perfettoEvent struct {
+    ts    int64
+    entry *LogcatEntry
+}
+
+type perfettoTrace struct {
+    events    []perfettoEvent
+    processes map[string]string // Command line by PID
+}
+
+// One field of a protobuf message
+type protoField struct {
+    num   uint64
+    wire  uint64
+    value uint64 // Varint and fixed values
+    data  []byte // Length-delimited values
+}
+
+// Reads the log entries of the android_log data source of a Perfetto trace.
+// The package of the entries is taken from the process tree of the trace, when recorded.
+func ReadPerfettoTrace(r io.Reader) ([]*LogcatEntry, error) {
+    trace := &perfettoTrace{
+        events:    []perfettoEvent{},
+        processes: map[string]string{},
+    }
+
+    if err := trace.readPackets(bufio.NewReader(r)); err != nil {
+        return nil, fmt.Errorf("invalid Perfetto trace: %s", err)
+    }
+
+    sort.SliceStable(trace.events, func(i, j int) bool {
+        return trace.events[i].ts < trace.events[j].ts
+    })
+
+    entries := make([]*LogcatEntry, 0, len(trace.events))
+    for _, ev := range trace.events {
+        if ev.entry.Package == "" {
+            ev.entry.Package = trace.processes[ev.entry.PID]
+        }
+        entries = append(entries, ev.entry)
+    }
+
+    return entries, nil
+}
+
+// Reads the packets of a Trace message without loading the whole trace
+func (t *perfettoTrace) readPackets(r *bufio.Reader) error {
+    for {
+        key, err := binary.ReadUvarint(r)
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+
+        if key&7 != wireBytes {
+            if err := skipField(r, key&7); err != nil {
+                return err
+            }
+            continue
+        }
+
+        size, err := binary.ReadUvarint(r)
+        if err != nil {
+            return err
+        }
+        if size > math.MaxInt64 {
+            return fmt.Errorf("field too large (%d bytes)", size)
+        }
+
+        // The other fields of the Trace are skipped without loading them
+        if key>>3 != 1 {
+            if _, err := io.CopyN(io.Discard, r, int64(size)); err != nil {
+                return unexpectedEOF(err)
+            }
+            continue
+        }
+
+        if size > perfettoMaxPacket {
+            return fmt.Errorf("packet too large (%d bytes)", size)
+        }
+
+        // The buffer grows with the data read, so a truncated trace does not allocate the size it claims
+        data := bytes.Buffer{}
+        if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+            return unexpectedEOF(err)
+        }
+        if err := t.readPacket(data.Bytes()); err != nil {
+            return err
+        }
+    }
+}
+
+// Reads one TracePacket
+func (t *perfettoTrace) readPacket(data []byte) error {
+    return eachField(data, func(f protoField) error {
+        switch {
+        case f.num == 2 && f.wire == wireBytes:
+            return t.readProcessTree(f.data)
+
+        case f.num == 39 && f.wire == wireBytes:
+            return eachField(f.data, func(ev protoField) error {
+                if ev.num == 1 && ev.wire == wireBytes {
+                    return t.readLogEvent(ev.data)
+                }
+                return nil
+            })
+
+        case f.num == 50 && f.wire == wireBytes:
+            zr, err := zlib.NewReader(bytes.NewReader(f.data))
+            if err != nil {
+                return err
+            }
+            defer zr.Close()
+            return t.readPackets(bufio.NewReader(zr))
+        }
+        return nil
+    })
+}
+
+// Reads one LogEvent as an entry
+func (t *perfettoTrace) readLogEvent(data []byte) error {
+    var pid, tid, prio uint64
+    var ts int64
+    var tag, message string
+    args := []string{}
+
+    err := eachField(data, func(f protoField) error {
+        switch f.num {
+        case 2:
+            pid = f.value
+        case 3:
+            tid = f.value
+        case 5:
+            ts = int64(f.value)
+        case 6:
+            tag = string(f.data)
+        case 7:
+            prio = f.value
+        case 8:
+            message = string(f.data)
+        case 9:
+            if f.wire == wireBytes {
+                arg, err := readLogArg(f.data)
+                if err != nil {
+                    return err
+                }
+                args = append(args, arg)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+
+    // The binary events (events buffer) have the arguments instead of a message
+    if message == "" && len(args) > 0 {
+        message = strings.Join(args, " ")
+    }
+
+    level, ok := perfettoLevels[prio]
+    if !ok {
+        level = "V"
+    }
+
+    stamp := time.Unix(0, ts).Local()
+    t.events = append(t.events, perfettoEvent{
+        ts: ts,
+        entry: &LogcatEntry{
+            Date:    stamp.Format("01-02"),
+            Time:    stamp.Format("15:04:05.000"),
+            Level:   level,
+            Tag:     tag,
+            PID:     fmt.Sprint(int32(pid)),
+            TID:     fmt.Sprint(int32(tid)),
+            Message: strings.TrimRight(message, "\n"),
+        },
+    })
+
+    return nil
+}
+
+// Reads one argument of a binary event as name=value
+func readLogArg(data []byte) (string, error) {
+    name, value := "", ""
+    err := eachField(data, func(f protoField) error {
+        switch f.num {
+        case 1:
+            name = string(f.data)
+        case 2:
+            value = fmt.Sprint(int64(f.value))
+        case 3:
+            value = fmt.Sprint(math.Float32frombits(uint32(f.value)))
+        case 4:
+            value = string(f.data)
+        }
+        return nil
+    })
+
+    return name + "=" + value, err
+}
+
+// Reads the processes of a ProcessTree, the first argument of the command line is the package of the apps
+func (t *perfettoTrace) readProcessTree(data []byte) error {
+    return eachField(data, func(f protoField) error {
+        if f.num != 1 || f.wire != wireBytes {
+            return nil
+        }
+
+        var pid uint64
+        cmdline := []string{}
+        err := eachField(f.data, func(p protoField) error {
+            switch p.num {
+            case 1:
+                pid = p.value
+            case 3:
+                cmdline = append(cmdline, string(p.data))
+            }
+            return nil
+        })
+        if err != nil {
+            return err
+        }
+
+        if len(cmdline) > 0 && cmdline[0] != "" {
+            t.processes[fmt.Sprint(int32(pid))] = cmdline[0]
+        }
+        return nil
+    })
+}
+
+// Calls fn for every field of a message
+func eachField(data []byte, fn func(f protoField) error) error {
+    for len(data) > 0 {
+        key, n := binary.Uvarint(data)
+        if n <= 0 {
+            return fmt.Errorf("invalid field key")
+        }
+        data = data[n:]
+
+        f := protoField{num: key >> 3, wire: key & 7}
+        switch f.wire {
+        case wireVarint:
+            f.value, n = binary.Uvarint(data)
+            if n <= 0 {
+                return fmt.Errorf("invalid varint")
+            }
+            data = data[n:]
+        case wireFixed64:
+            if len(data) < 8 {
+                return io.ErrUnexpectedEOF
+            }
+            f.value = binary.LittleEndian.Uint64(data)
+            data = data[8:]
+        case wireFixed32:
+            if len(data) < 4 {
+                return io.ErrUnexpectedEOF
+            }
+            f.value = uint64(binary.LittleEndian.Uint32(data))
+            data = data[4:]
+        case wireBytes:
+            size, n := binary.Uvarint(data)
+            if n <= 0 || size > uint64(len(data)-n) {
+                return io.ErrUnexpectedEOF
+            }
+            f.data = data[n : n+int(size)]
+            data = data[n+int(size):]
+        default:
+            return fmt.Errorf("unsupported wire type %d", f.wire)
+        }
+
+        if err := fn(f); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// Skips a field that is not length-delimited
+func skipField(r *bufio.Reader, wire uint64) error {
+    switch wire {
+    case wireVarint:
+        _, err := binary.ReadUvarint(r)
+        return err
+    case wireFixed64:
+        _, err := r.Discard(8)
+        return err
+    case wireFixed32:
+        _, err := r.Discard(4)
+        return err
+    }
+
+    return fmt.Errorf("unsupported wire type %d", wire)
+}
+
+// A field cut by the end of the file is an error
+func unexpectedEOF(err error) error {
+    if err == io.EOF {
+        return io.ErrUnexpectedEOF
+    }
+    return err
+}
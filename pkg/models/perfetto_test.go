@@ -0,0 +1,91 @@
+package models
+
+import (
+    "bytes"
+    "encoding/binary"
+    "strings"
+    "testing"
+)
+
+// Encodes a length-delimited field
+func protoBytes(num uint64, data []byte) []byte {
+    out := binary.AppendUvarint(nil, num<<3|wireBytes)
+    out = binary.AppendUvarint(out, uint64(len(data)))
+    return append(out, data...)
+}
+
+// Encodes a varint field
+func protoVarint(num uint64, value uint64) []byte {
+    out := binary.AppendUvarint(nil, num<<3|wireVarint)
+    return binary.AppendUvarint(out, value)
+}
+
+func logPacket(pid uint64, tag string, message string) []byte {
+    event := bytes.Join([][]byte{
+        protoVarint(2, pid),
+        protoVarint(3, pid),
+        protoVarint(5, 1000),
+        protoBytes(6, []byte(tag)),
+        protoVarint(7, 4),
+        protoBytes(8, []byte(message)),
+    }, nil)
+    return protoBytes(1, protoBytes(39, protoBytes(1, event)))
+}
+
+func TestReadPerfettoTrace(t *testing.T) {
+    process := bytes.Join([][]byte{
+        protoVarint(1, 1234),
+        protoBytes(3, []byte("com.acme.app")),
+    }, nil)
+    trace := bytes.Join([][]byte{
+        protoBytes(1, protoBytes(2, protoBytes(1, process))),
+        // A field of the Trace that is not a packet is skipped
+        protoBytes(2, []byte("skipped")),
+        logPacket(1234, "Acme", "started"),
+    }, nil)
+
+    entries, err := ReadPerfettoTrace(bytes.NewReader(trace))
+    if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+    }
+    if len(entries) != 1 {
+        t.Fatalf("got %d entries, want 1", len(entries))
+    }
+
+    e := entries[0]
+    if e.Level != "I" || e.Tag != "Acme" || e.PID != "1234" || e.Package != "com.acme.app" || e.Message != "started" {
+        t.Errorf("unexpected entry %+v", e)
+    }
+}
+
+func TestReadPerfettoTraceSizes(t *testing.T) {
+    packet := logPacket(1, "Acme", "started")
+
+    // A packet claiming 2 GiB, with only a few bytes after it
+    huge := binary.AppendUvarint(nil, 1<<3|wireBytes)
+    huge = binary.AppendUvarint(huge, 2<<30)
+    huge = append(huge, "data"...)
+
+    // A field that is not a packet claiming more than the file
+    skipped := binary.AppendUvarint(nil, 2<<3|wireBytes)
+    skipped = binary.AppendUvarint(skipped, 1<<40)
+
+    tests := []struct {
+        name  string
+        trace []byte
+        err   string
+    }{
+        {"packet too large", huge, "packet too large"},
+        {"truncated packet", packet[:len(packet)-3], "unexpected EOF"},
+        {"truncated field", skipped, "unexpected EOF"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := ReadPerfettoTrace(bytes.NewReader(tt.trace))
+            if err == nil || !strings.Contains(err.Error(), tt.err) {
+                t.Errorf("got error %v, want %q", err, tt.err)
+            }
+        })
+    }
+}
@@ -0,0 +1,290 @@
+package models
+
+import (
+    "bytes"
+    "encoding/json"
+    "encoding/xml"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/fatih/color"
+)
+
+const (
+    // The kinds of the message lines
+    lineText = ""
+    lineJSON = "json"
+    lineXML  = "xml"
+)
+
+var (
+    // Pretty print the JSON and the XML found in the messages (see --pretty-json and --pretty-xml)
+    prettyJSON = false
+    prettyXML  = false
+
+    // The colors of the pretty printed blocks by token (key, string, number, literal, tag, attr), set by the theme
+    colorSyntax = map[string]*color.Color{}
+
+    // The tokens of a pretty printed JSON line: a key (string followed by ':'), a string, a number or a literal
+    reJSONToken = regexp.MustCompile(`("(?:[^"\\]|\\.)*")(\s*:)?|-?\b\d+(?:\.\d+)?(?:[eE][+-]?\d+)?\b|\b(?:true|false|null)\b`)
+
+    // The tokens of a pretty printed XML line: an element name or an attribute with its value
+    reXMLToken = regexp.MustCompile(`(</?)([\w:.-]+)|([\w:.-]+)=("[^"]*"|'[^']*')`)
+)
+
+// Sets if the JSON and the XML found in the messages are pretty printed
+func SetPrettyPrint(jsonMessages bool, xmlMessages bool) {
+    prettyJSON = jsonMessages
+    prettyXML = xmlMessages
+}
+
+// Gets the message with the JSON/XML fragments pretty printed on their own lines,
+// and the kind of each line. Malformed fragments are left as they are.
+func prettyMessage(msg string) (string, []string) {
+    if !prettyJSON && !prettyXML {
+        return msg, nil
+    }
+
+    lines := []string{""}
+    kinds := []string{lineText}
+    found := false
+
+    addText := func(text string) {
+        parts := strings.Split(text, "\n")
+        lines[len(lines)-1] += parts[0]
+        for _, p := range parts[1:] {
+            lines = append(lines, p)
+            kinds = append(kinds, lineText)
+        }
+    }
+
+    addBlock := func(block string, kind string) {
+        // The block starts on its own line, unless the current line is empty
+        last := len(lines) - 1
+        if strings.TrimSpace(lines[last]) == "" {
+            lines = lines[:last]
+            kinds = kinds[:last]
+        }else{
+            lines[last] = strings.TrimRight(lines[last], " ")
+        }
+        for _, l := range strings.Split(block, "\n") {
+            lines = append(lines, l)
+            kinds = append(kinds, kind)
+        }
+        lines = append(lines, "")
+        kinds = append(kinds, lineText)
+        found = true
+    }
+
+    pos := 0
+    for i := 0; i < len(msg); i++ {
+        var block string
+        var end int
+        var kind string
+
+        switch {
+        case prettyJSON && (msg[i] == '{' || msg[i] == '['):
+            block, end = prettyJSONAt(msg, i)
+            kind = lineJSON
+        case prettyXML && msg[i] == '<' && i+1 < len(msg) && isNameStart(msg[i+1]):
+            block, end = prettyXMLAt(msg, i)
+            kind = lineXML
+        }
+
+        if block == "" {
+            continue
+        }
+
+        addText(msg[pos:i])
+        addBlock(block, kind)
+        pos = end
+        i = end - 1
+
+        // The text after the block starts without the spaces
+        for pos < len(msg) && msg[pos] == ' ' {
+            pos++
+            i++
+        }
+    }
+
+    if !found {
+        return msg, nil
+    }
+
+    addText(msg[pos:])
+
+    // No empty line after the last block
+    if len(lines) > 1 && lines[len(lines)-1] == "" {
+        lines = lines[:len(lines)-1]
+        kinds = kinds[:len(kinds)-1]
+    }
+
+    return strings.Join(lines, "\n"), kinds
+}
+
+// Pretty prints the JSON object or array starting at the position, returns its end.
+// Returns an empty block when it is not JSON or is too simple to be worth it (e.g. [1]).
+func prettyJSONAt(msg string, start int) (string, int) {
+    dec := json.NewDecoder(strings.NewReader(msg[start:]))
+    raw := json.RawMessage{}
+    if err := dec.Decode(&raw); err != nil {
+        return "", 0
+    }
+
+    if raw[0] == '{' && len(bytes.TrimSpace(raw[1:len(raw)-1])) == 0 {
+        return "", 0
+    }
+    if raw[0] == '[' && !bytes.ContainsAny(raw[1:], "{[") {
+        return "", 0
+    }
+
+    buf := bytes.Buffer{}
+    if err := json.Indent(&buf, raw, "", "  "); err != nil {
+        return "", 0
+    }
+
+    return buf.String(), start + int(dec.InputOffset())
+}
+
+// Pretty prints the XML element starting at the position, returns its end.
+// Returns an empty block when it is not XML or has no child elements.
+func prettyXMLAt(msg string, start int) (string, int) {
+    dec := xml.NewDecoder(strings.NewReader(msg[start:]))
+
+    tokens := []xml.Token{}
+    depth := 0
+    maxDepth := 0
+    for {
+        tok, err := dec.RawToken()
+        if err != nil {
+            return "", 0
+        }
+
+        switch t := tok.(type) {
+        case xml.StartElement:
+            depth++
+            if depth > maxDepth {
+                maxDepth = depth
+            }
+            // Keep the prefixes as they were, the encoder would turn them into namespaces
+            t.Name = xml.Name{Local: xmlName(t.Name)}
+            attrs := []xml.Attr{}
+            for _, a := range t.Attr {
+                attrs = append(attrs, xml.Attr{Name: xml.Name{Local: xmlName(a.Name)}, Value: a.Value})
+            }
+            t.Attr = attrs
+            tokens = append(tokens, t)
+        case xml.EndElement:
+            depth--
+            tokens = append(tokens, xml.EndElement{Name: xml.Name{Local: xmlName(t.Name)}})
+        case xml.CharData:
+            if strings.TrimSpace(string(t)) != "" {
+                tokens = append(tokens, t.Copy())
+            }
+        case xml.Comment:
+            tokens = append(tokens, t.Copy())
+        default:
+            // Only elements inside the message
+            return "", 0
+        }
+
+        if depth <= 0 {
+            break
+        }
+    }
+
+    if maxDepth < 2 {
+        return "", 0
+    }
+
+    buf := bytes.Buffer{}
+    enc := xml.NewEncoder(&buf)
+    enc.Indent("", "  ")
+    for _, t := range tokens {
+        if err := enc.EncodeToken(t); err != nil {
+            return "", 0
+        }
+    }
+    if err := enc.Flush(); err != nil {
+        return "", 0
+    }
+
+    return buf.String(), start + int(dec.InputOffset())
+}
+
+func xmlName(n xml.Name) string {
+    if n.Space != "" {
+        return n.Space + ":" + n.Local
+    }
+    return n.Local
+}
+
+func isNameStart(c byte) bool {
+    return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+// Gets the spans of the syntax colors of a pretty printed line
+func syntaxSpans(text string, kind string) []span {
+    spans := []span{}
+    add := func(start int, end int, name string) {
+        if c, ok := colorSyntax[name]; ok && end > start {
+            spans = append(spans, span{start: start, end: end, color: c})
+        }
+    }
+
+    switch kind {
+    case lineJSON:
+        for _, m := range reJSONToken.FindAllStringSubmatchIndex(text, -1) {
+            switch {
+            case m[2] >= 0 && m[4] >= 0:
+                add(m[2], m[3], "key")
+            case m[2] >= 0:
+                add(m[2], m[3], "string")
+            case text[m[0]] == 't' || text[m[0]] == 'f' || text[m[0]] == 'n':
+                add(m[0], m[1], "literal")
+            default:
+                add(m[0], m[1], "number")
+            }
+        }
+    case lineXML:
+        for _, m := range reXMLToken.FindAllStringSubmatchIndex(text, -1) {
+            if m[4] >= 0 {
+                add(m[4], m[5], "tag")
+            }else{
+                add(m[6], m[7], "attr")
+                add(m[8], m[9], "string")
+            }
+        }
+    }
+
+    return spans
+}
+
+// Colors a message line with the base color, the highlight rules and,
+// for the pretty printed lines, the syntax colors. The highlight rules win.
+func highlightLine(text string, base *color.Color, kind string) string {
+    if kind == lineText {
+        return highlight(text, base)
+    }
+
+    spans := highlightSpans(text)
+    for _, s := range syntaxSpans(text, kind) {
+        overlaps := false
+        for _, h := range spans {
+            if s.start < h.end && h.start < s.end {
+                overlaps = true
+                break
+            }
+        }
+        if !overlaps {
+            spans = append(spans, s)
+        }
+    }
+
+    sort.Slice(spans, func(i, j int) bool {
+        return spans[i].start < spans[j].start
+    })
+
+    return paint(text, base, spans)
+}
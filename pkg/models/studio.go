@@ -0,0 +1,227 @@
+package models
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "strings"
+    "time"
+)
+
+// The .logcat files of Android Studio are one JSON object with the metadata of
+// the session and the messages:
+//
+//    {"metadata": {"device": {...}, "filter": "", "projectApplicationIds": []},
+//     "logcatMessages": [{"header": {"logLevel": "INFO", "pid": 1234, "tid": 1234,
+//         "applicationId": "com.acme.app", "processName": "com.acme.app", "tag": "Tag",
+//         "timestamp": {"seconds": 1700000000, "nanos": 123000000}}, "message": "..."}]}
+
+type studioFile struct {
+    Metadata       studioMetadata  `json:"metadata"`
+    LogcatMessages []studioMessage `json:"logcatMessages"`
+}
+
+type studioMetadata struct {
+    Device                *studioDevice `json:"device,omitempty"`
+    Filter                string        `json:"filter"`
+    ProjectApplicationIds []string      `json:"projectApplicationIds"`
+}
+
+type studioDevice struct {
+    DeviceId     string `json:"deviceId"`
+    Name         string `json:"name"`
+    SerialNumber string `json:"serialNumber"`
+    IsOnline     bool   `json:"isOnline"`
+    Release      string `json:"release"`
+    Sdk          int    `json:"sdk"`
+    FeatureLevel int    `json:"featureLevel"`
+    IsEmulator   bool   `json:"isEmulator"`
+    Manufacturer string `json:"manufacturer"`
+    Model        string `json:"model"`
+    Type         string `json:"type"`
+}
+
+type studioMessage struct {
+    Header  studioHeader `json:"header"`
+    Message string       `json:"message"`
+}
+
+type studioHeader struct {
+    LogLevel      string          `json:"logLevel"`
+    Pid           int             `json:"pid"`
+    Tid           int             `json:"tid"`
+    ApplicationId string          `json:"applicationId"`
+    ProcessName   string          `json:"processName"`
+    Tag           string          `json:"tag"`
+    Timestamp     studioTimestamp `json:"timestamp"`
+}
+
+type studioTimestamp struct {
+    Seconds int64 `json:"seconds"`
+    Nanos   int64 `json:"nanos"`
+}
+
+var (
+    // The levels of the .logcat files by logcat level
+    studioLevels = map[string]string{
+        "V": "VERBOSE",
+        "D": "DEBUG",
+        "I": "INFO",
+        "W": "WARN",
+        "E": "ERROR",
+        "F": "ASSERT",
+    }
+)
+
+// Reads the entries and the device of an Android Studio .logcat file
+func ReadStudioLogcat(r io.Reader) ([]*LogcatEntry, *DeviceInfo, error) {
+    file := studioFile{}
+    if err := json.NewDecoder(r).Decode(&file); err != nil {
+        return nil, nil, fmt.Errorf("invalid .logcat file: %s", err)
+    }
+
+    var device *DeviceInfo
+    if d := file.Metadata.Device; d != nil {
+        device = &DeviceInfo{
+            Serial:       d.SerialNumber,
+            Manufacturer: d.Manufacturer,
+            Model:        d.Model,
+            Release:      d.Release,
+            SDK:          d.Sdk,
+            Emulator:     d.IsEmulator,
+        }
+    }
+
+    entries := []*LogcatEntry{}
+    for _, m := range file.LogcatMessages {
+        ts := time.Unix(m.Header.Timestamp.Seconds, m.Header.Timestamp.Nanos).Local()
+
+        level := "V"
+        for l, name := range studioLevels {
+            if strings.EqualFold(name, m.Header.LogLevel) {
+                level = l
+                break
+            }
+        }
+
+        pkg := m.Header.ApplicationId
+        if pkg == "" {
+            pkg = m.Header.ProcessName
+        }
+
+        entries = append(entries, &LogcatEntry{
+            Date:    ts.Format("01-02"),
+            Time:    ts.Format("15:04:05.000"),
+            Level:   level,
+            Tag:     m.Header.Tag,
+            PID:     fmt.Sprint(m.Header.Pid),
+            TID:     fmt.Sprint(m.Header.Tid),
+            Message: m.Message,
+            Package: pkg,
+        })
+    }
+
+    return entries, device, nil
+}
+
+// StudioWriter writes the entries as an Android Studio .logcat file.
+// The file is only valid after Close, which ends the JSON object.
+type StudioWriter struct {
+    w     io.Writer
+    count int
+}
+
+// Starts a .logcat file with the device (may be nil) and the application ids of the session
+func NewStudioWriter(w io.Writer, device *DeviceInfo, applicationIds []string) (*StudioWriter, error) {
+    meta := studioMetadata{
+        Filter:                "",
+        ProjectApplicationIds: applicationIds,
+    }
+    if meta.ProjectApplicationIds == nil {
+        meta.ProjectApplicationIds = []string{}
+    }
+    if filter := strings.Join(applicationIds, " "); filter != "" {
+        meta.Filter = "package:" + filter
+    }
+
+    if device != nil {
+        meta.Device = &studioDevice{
+            DeviceId:     device.Serial,
+            Name:         device.Name(),
+            SerialNumber: device.Serial,
+            IsOnline:     false,
+            Release:      device.Release,
+            Sdk:          device.SDK,
+            FeatureLevel: device.SDK,
+            IsEmulator:   device.Emulator,
+            Manufacturer: device.Manufacturer,
+            Model:        device.Model,
+            Type:         "HANDHELD",
+        }
+    }
+
+    data, err := json.Marshal(meta)
+    if err != nil {
+        return nil, err
+    }
+
+    if _, err := fmt.Fprintf(w, "{\n  \"metadata\": %s,\n  \"logcatMessages\": [", data); err != nil {
+        return nil, err
+    }
+
+    return &StudioWriter{w: w}, nil
+}
+
+// Writes one entry
+func (sw *StudioWriter) Write(entry *LogcatEntry) error {
+    ts, err := entry.Timestamp()
+    if err != nil {
+        ts = time.Now()
+    }
+
+    level, ok := studioLevels[entry.Level]
+    if !ok {
+        level = "VERBOSE"
+    }
+
+    var pid, tid int
+    fmt.Sscan(entry.PID, &pid)
+    fmt.Sscan(entry.TID, &tid)
+
+    // The repetitions of the collapsed entries are not a part of the format
+    msg := entry.Message + entry.RepeatSuffix()
+
+    data, err := json.Marshal(studioMessage{
+        Header: studioHeader{
+            LogLevel:      level,
+            Pid:           pid,
+            Tid:           tid,
+            ApplicationId: entry.Package,
+            ProcessName:   entry.Package,
+            Tag:           strings.TrimSpace(entry.Tag),
+            Timestamp: studioTimestamp{
+                Seconds: ts.Unix(),
+                Nanos:   int64(ts.Nanosecond()),
+            },
+        },
+        Message: msg,
+    })
+    if err != nil {
+        return err
+    }
+
+    sep := ","
+    if sw.count == 0 {
+        sep = ""
+    }
+    sw.count++
+
+    _, err = fmt.Fprintf(sw.w, "%s\n    %s", sep, data)
+    return err
+}
+
+// Ends the JSON object of the file
+func (sw *StudioWriter) Close() error {
+    _, err := fmt.Fprint(sw.w, "\n  ]\n}\n")
+    return err
+}
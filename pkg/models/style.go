@@ -0,0 +1,173 @@
+package models
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+
+    "github.com/helviojunior/adbcat/internal/ascii"
+    "github.com/fatih/color"
+)
+
+var (
+    // The color depth of the terminal, styles are degraded to fit it
+    colorDepth = ascii.DepthTrueColor
+
+    // Named foreground colors accepted in a style
+    styleColors = map[string]color.Attribute{
+        "black":   color.FgBlack,
+        "red":     color.FgRed,
+        "green":   color.FgGreen,
+        "yellow":  color.FgYellow,
+        "blue":    color.FgBlue,
+        "magenta": color.FgMagenta,
+        "cyan":    color.FgCyan,
+        "white":   color.FgWhite,
+        "gray":    color.FgHiBlack,
+        "grey":    color.FgHiBlack,
+    }
+
+    // Text attributes accepted in a style
+    styleAttributes = map[string]color.Attribute{
+        "bold":      color.Bold,
+        "faint":     color.Faint,
+        "dim":       color.Faint,
+        "italic":    color.Italic,
+        "underline": color.Underline,
+        "blink":     color.BlinkSlow,
+        "reverse":   color.ReverseVideo,
+        "strike":    color.CrossedOut,
+    }
+)
+
+// Sets the color depth used by the styles parsed from now on
+func SetColorDepth(depth int) {
+    colorDepth = depth
+}
+
+// Parses a style like "bold,yellow,on-blue" into a color.
+//
+// Each term, separated by ',', '+' or spaces, is one of:
+//   - an attribute: bold, faint, italic, underline, blink, reverse, strike
+//   - a color: black, red, green, yellow, blue, magenta, cyan, white, gray
+//   - a bright color: hi-red, bright-red...
+//   - a 256 color index (208) or a RGB color (#ff8800)
+//
+// Prefix a color with "on-" (or "bg-") to use it as background.
+func ParseStyle(style string) (*color.Color, error) {
+    terms := strings.FieldsFunc(strings.ToLower(style), func(r rune) bool {
+        return r == ',' || r == '+' || r == ' '
+    })
+
+    if len(terms) == 0 {
+        return nil, fmt.Errorf("empty style")
+    }
+
+    c := color.New()
+    for _, term := range terms {
+        if attr, ok := styleAttributes[term]; ok {
+            c.Add(attr)
+            continue
+        }
+
+        bg := false
+        for _, p := range []string{"on-", "bg-", "on_", "bg_"} {
+            if strings.HasPrefix(term, p) {
+                bg = true
+                term = term[len(p):]
+                break
+            }
+        }
+
+        if err := addStyleColor(c, term, bg); err != nil {
+            return nil, fmt.Errorf("invalid style '%s': %s", style, err)
+        }
+    }
+
+    return c, nil
+}
+
+// Adds the color named by term to c, as foreground or background
+func addStyleColor(c *color.Color, term string, bg bool) error {
+    // RGB color like #ff8800
+    if strings.HasPrefix(term, "#") {
+        rgb, err := strconv.ParseUint(term[1:], 16, 32)
+        if err != nil || len(term) != 7 {
+            return fmt.Errorf("invalid RGB color '%s'", term)
+        }
+        addRGB(c, int(rgb>>16&0xff), int(rgb>>8&0xff), int(rgb&0xff), bg)
+        return nil
+    }
+
+    // 256 color index like 208
+    if n, err := strconv.Atoi(term); err == nil {
+        if n < 0 || n > 255 {
+            return fmt.Errorf("invalid color index '%s'", term)
+        }
+        if colorDepth < ascii.Depth256 {
+            r, g, b := ascii.Color256ToRGB(n)
+            addRGB(c, r, g, b, bg)
+        } else if bg {
+            c.Add(48, 5, color.Attribute(n))
+        } else {
+            c.Add(38, 5, color.Attribute(n))
+        }
+        return nil
+    }
+
+    hi := false
+    for _, p := range []string{"hi-", "bright-", "hi", "bright"} {
+        if strings.HasPrefix(term, p) {
+            if _, ok := styleColors[term[len(p):]]; ok {
+                hi = true
+                term = term[len(p):]
+                break
+            }
+        }
+    }
+
+    attr, ok := styleColors[term]
+    if !ok {
+        return fmt.Errorf("unknown color '%s'", term)
+    }
+
+    // FgHiBlack is already a bright color
+    if hi && attr != color.FgHiBlack {
+        attr += color.FgHiBlack - color.FgBlack
+    }
+    if bg {
+        attr += color.BgBlack - color.FgBlack
+    }
+    c.Add(attr)
+
+    return nil
+}
+
+// Adds a RGB color to c degraded to the color depth of the terminal
+func addRGB(c *color.Color, r, g, b int, bg bool) {
+    switch {
+    case colorDepth >= ascii.DepthTrueColor:
+        if bg {
+            c.AddBgRGB(r, g, b)
+        } else {
+            c.AddRGB(r, g, b)
+        }
+    case colorDepth >= ascii.Depth256:
+        n := color.Attribute(ascii.RGBTo256(r, g, b))
+        if bg {
+            c.Add(48, 5, n)
+        } else {
+            c.Add(38, 5, n)
+        }
+    default:
+        n := ascii.RGBTo16(r, g, b)
+        attr := color.FgBlack + color.Attribute(n)
+        if n >= 8 {
+            attr = color.FgHiBlack + color.Attribute(n-8)
+        }
+        if bg {
+            attr += color.BgBlack - color.FgBlack
+        }
+        c.Add(attr)
+    }
+}
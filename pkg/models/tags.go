@@ -0,0 +1,64 @@
+package models
+
+import (
+    "fmt"
+    "hash/fnv"
+    "strings"
+
+    "github.com/fatih/color"
+)
+
+var (
+    // A slice of colors for the tags, each tag gets one of them by the hash of its name.
+    // Set by the theme.
+    colorTags = []*color.Color{}
+
+    // Well known system tags always get the same color (as pidcat does). Set by the theme.
+    knownTags = map[string]*color.Color{}
+
+    // The tag colors set by the user, they win over the known tags
+    tagColorOverrides = map[string]*color.Color{}
+)
+
+// Gets the color of a tag.
+//
+// The user overrides are checked first, then the known system tags.
+// Any other tag gets a color from the palette by the hash of its name,
+// so it keeps the same color across runs.
+func TagColor(tag string) *color.Color {
+    tag = strings.TrimSpace(tag)
+
+    if c, ok := tagColorOverrides[tag]; ok {
+        return c
+    }
+
+    if c, ok := knownTags[tag]; ok {
+        return c
+    }
+
+    h := fnv.New32a()
+    h.Write([]byte(tag))
+
+    return colorTags[h.Sum32()%uint32(len(colorTags))]
+}
+
+// Sets the color of a tag with a style like "bold,yellow"
+func SetTagColor(tag string, style string) error {
+    c, err := ParseStyle(style)
+    if err != nil {
+        return err
+    }
+
+    tagColorOverrides[strings.TrimSpace(tag)] = c
+    return nil
+}
+
+// Parses and sets a tag color in the format "Tag=style" like "OkHttp=bold,magenta"
+func ParseTagColor(rule string) error {
+    p := strings.SplitN(rule, "=", 2)
+    if len(p) != 2 || strings.TrimSpace(p[0]) == "" {
+        return fmt.Errorf("invalid tag color '%s', use the format 'Tag=style'", rule)
+    }
+
+    return SetTagColor(p[0], p[1])
+}
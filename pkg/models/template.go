@@ -0,0 +1,187 @@
+package models
+
+import (
+    "fmt"
+    "strings"
+    "sync"
+    "text/template"
+    "time"
+
+    "github.com/fatih/color"
+    "github.com/helviojunior/adbcat/internal/ascii"
+)
+
+var (
+    // The template of the output lines (see --template), nil to use the fixed columns
+    lineTemplate      *template.Template
+    lineTemplatePlain *template.Template
+
+    // The styles used by the templates, parsed once
+    templateStyles      = map[string]*color.Color{}
+    templateStylesMutex sync.Mutex
+)
+
+// The layouts accepted by the time helper when the value is a string
+var templateTimeLayouts = []string{
+    "01-02 15:04:05.000",
+    "15:04:05.000",
+    time.RFC3339Nano,
+}
+
+// Checks if the line template uses the fields extracted from the messages
+func LineTemplateUsesFields() bool {
+    return lineTemplate != nil && strings.Contains(lineTemplate.Root.String(), ".Fields")
+}
+
+// Sets the template of the output lines, with the text/template syntax over the entry fields
+// (e.g. '{{.Time | time "15:04:05"}} {{.Tag | pad 20}} {{.Message}}'). An empty text
+// goes back to the fixed columns.
+func SetLineTemplate(text string) error {
+    if text == "" {
+        lineTemplate = nil
+        lineTemplatePlain = nil
+        return nil
+    }
+
+    // The missing fields (e.g. {{.Fields.status}}) are empty
+    t1, err := template.New("line").Option("missingkey=zero").Funcs(templateFuncs(true)).Parse(text)
+    if err != nil {
+        return fmt.Errorf("invalid template: %s", err)
+    }
+    t2, err := template.New("line").Option("missingkey=zero").Funcs(templateFuncs(false)).Parse(text)
+    if err != nil {
+        return fmt.Errorf("invalid template: %s", err)
+    }
+
+    // Catch the unknown fields and the invalid styles now instead of on every line
+    sample := LogcatEntry{Date: "01-01", Time: "00:00:00.000", Level: "I", Tag: "adbcat", PID: "1", TID: "1"}
+    if err := t1.Execute(&strings.Builder{}, sample); err != nil {
+        return fmt.Errorf("invalid template: %s", err)
+    }
+
+    lineTemplate = t1
+    lineTemplatePlain = t2
+    return nil
+}
+
+// Formats the entry with the line template, with or without colors
+func (entry LogcatEntry) formatTemplate(colors bool) (string, error) {
+    t := lineTemplatePlain
+    if colors {
+        t = lineTemplate
+    }
+
+    // The tags come padded from logcat
+    entry.Tag = strings.TrimSpace(entry.Tag)
+
+    sb := strings.Builder{}
+    if err := t.Execute(&sb, entry); err != nil {
+        return "", err
+    }
+
+    text := sb.String()
+    if !colors {
+        text = ascii.ScapeAnsi(text)
+    }
+
+    return text + entry.RepeatSuffix(), nil
+}
+
+// The helpers of the templates. Without colors the color helpers keep the text as is.
+func templateFuncs(colors bool) template.FuncMap {
+    funcs := template.FuncMap{
+        "pad":   templatePad,
+        "trunc": templateTrunc,
+        "time":  templateTime,
+        "upper": strings.ToUpper,
+        "lower": strings.ToLower,
+        "trim":  strings.TrimSpace,
+    }
+
+    if colors {
+        funcs["color"] = templateColor
+        funcs["tagcolor"] = func(text string) string {
+            return TagColor(strings.TrimSpace(text)).Sprint(text)
+        }
+        funcs["levelcolor"] = func(level string, text string) string {
+            return highlight(text, colorLevel[LevelMap[level]])
+        }
+        funcs["badge"] = func(level string) string {
+            return colorLevelBadge[LevelMap[level]].Sprintf(" %s ", level)
+        }
+    }else{
+        funcs["color"] = func(style string, text string) (string, error) {
+            _, err := templateStyle(style)
+            return text, err
+        }
+        funcs["tagcolor"] = func(text string) string {
+            return text
+        }
+        funcs["levelcolor"] = func(level string, text string) string {
+            return text
+        }
+        funcs["badge"] = func(level string) string {
+            return fmt.Sprintf(" %s ", level)
+        }
+    }
+
+    return funcs
+}
+
+// Pads the text with spaces to the size, to the left when the size is negative
+func templatePad(size int, text string) string {
+    if size < 0 {
+        return padLeftWidth(text, -size)
+    }
+    return padRightWidth(text, size)
+}
+
+// Truncates the text to the size, ending with "..." when it was cut
+func templateTrunc(size int, text string) string {
+    if size <= 0 {
+        return text
+    }
+    return ellipsisWidth(text, size, "...")
+}
+
+// Formats a time (the entry time, as string, or its timestamp) with a Go layout
+func templateTime(layout string, value interface{}) (string, error) {
+    switch v := value.(type) {
+    case time.Time:
+        return v.Format(layout), nil
+    case string:
+        for _, l := range templateTimeLayouts {
+            if t, err := time.Parse(l, strings.TrimSpace(v)); err == nil {
+                return t.Format(layout), nil
+            }
+        }
+        return v, nil
+    default:
+        return "", fmt.Errorf("time: unsupported value %v", value)
+    }
+}
+
+// Colors the text with a style like "bold,red"
+func templateColor(style string, text string) (string, error) {
+    c, err := templateStyle(style)
+    if err != nil {
+        return "", err
+    }
+    return c.Sprint(text), nil
+}
+
+func templateStyle(style string) (*color.Color, error) {
+    templateStylesMutex.Lock()
+    defer templateStylesMutex.Unlock()
+
+    if c, ok := templateStyles[style]; ok {
+        return c, nil
+    }
+
+    c, err := ParseStyle(style)
+    if err != nil {
+        return nil, err
+    }
+    templateStyles[style] = c
+    return c, nil
+}
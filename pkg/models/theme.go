@@ -0,0 +1,294 @@
+package models
+
+import (
+    "fmt"
+    "os"
+    "strings"
+
+    "github.com/fatih/color"
+    "gopkg.in/yaml.v3"
+)
+
+// A Theme holds the styles (see ParseStyle) used by the colored output.
+//
+// Themes loaded from files only need to set the styles they change,
+// everything else comes from the Base theme (dark by default).
+type Theme struct {
+    Name      string            `yaml:"name"`
+    Base      string            `yaml:"base,omitempty"`
+    Levels    map[string]string `yaml:"levels,omitempty"`     // The message color by level (V,D,I,W,E,F)
+    Badges    map[string]string `yaml:"badges,omitempty"`     // The level badge color by level
+    Prefix    string            `yaml:"prefix,omitempty"`     // The time and PID/TID columns
+    Highlight string            `yaml:"highlight,omitempty"`  // The default highlight style
+    Tags      []string          `yaml:"tags,omitempty"`       // The palette of tag colors
+    KnownTags map[string]string `yaml:"known-tags,omitempty"` // Fixed colors of well known tags
+    Syntax    map[string]string `yaml:"syntax,omitempty"`     // The pretty printed JSON/XML by token (key, string, number, literal, tag, attr)
+}
+
+var (
+    // The theme for terminals with a dark background
+    ThemeDark = &Theme{
+        Name: "dark",
+        Levels: map[string]string{
+            "V": "white,on-black",
+            "D": "cyan,on-black",
+            "I": "green,on-black",
+            "W": "yellow,on-black",
+            "E": "red,on-black",
+            "F": "red,on-black",
+        },
+        Badges: map[string]string{
+            "V": "white,on-#3c3c3c",
+            "D": "cyan,on-#3c3c3c",
+            "I": "green,on-#3c3c3c",
+            "W": "yellow,on-#3c3c3c",
+            "E": "red,on-#3c3c3c",
+            "F": "red,on-#3c3c3c",
+        },
+        Prefix:    "bold,gray",
+        Highlight: "bold,black,on-yellow",
+        Tags: []string{
+            "red,on-black", "green,on-black", "yellow,on-black",
+            "blue,on-black", "magenta,on-black", "cyan,on-black",
+            "hi-red,on-black", "hi-green,on-black", "hi-yellow,on-black",
+            "hi-blue,on-black", "hi-magenta,on-black", "hi-cyan,on-black",
+        },
+        KnownTags: map[string]string{
+            "dalvikvm":        "white,on-black",
+            "Process":         "white,on-black",
+            "ActivityManager": "white,on-black",
+            "ActivityThread":  "white,on-black",
+            "AndroidRuntime":  "cyan,on-black",
+            "jdwp":            "white,on-black",
+            "StrictMode":      "white,on-black",
+            "DEBUG":           "yellow,on-black",
+        },
+        Syntax: map[string]string{
+            "key":     "hi-blue,on-black",
+            "string":  "green,on-black",
+            "number":  "magenta,on-black",
+            "literal": "yellow,on-black",
+            "tag":     "hi-blue,on-black",
+            "attr":    "cyan,on-black",
+        },
+    }
+
+    // The theme for terminals with a light background, it keeps the terminal background
+    ThemeLight = &Theme{
+        Name: "light",
+        Levels: map[string]string{
+            "V": "#585858",
+            "D": "#005f87",
+            "I": "#005f00",
+            "W": "#875f00",
+            "E": "#af0000",
+            "F": "bold,#af0000",
+        },
+        Badges: map[string]string{
+            "V": "black,on-#d0d0d0",
+            "D": "black,on-#87d7ff",
+            "I": "black,on-#afd7af",
+            "W": "black,on-#ffd787",
+            "E": "white,on-#d70000",
+            "F": "bold,white,on-#870000",
+        },
+        Prefix:    "#8a8a8a",
+        Highlight: "bold,black,on-#ffff5f",
+        Tags: []string{
+            "#af0000", "#008700", "#875f00", "#0000af", "#870087", "#008787",
+            "#d75f00", "#5f00af", "#005f87", "#5f8700", "#af005f", "#5f5f87",
+        },
+        KnownTags: map[string]string{
+            "dalvikvm":        "#4e4e4e",
+            "Process":         "#4e4e4e",
+            "ActivityManager": "#4e4e4e",
+            "ActivityThread":  "#4e4e4e",
+            "AndroidRuntime":  "#008787",
+            "jdwp":            "#4e4e4e",
+            "StrictMode":      "#4e4e4e",
+            "DEBUG":           "#875f00",
+        },
+        Syntax: map[string]string{
+            "key":     "#005f87",
+            "string":  "#008700",
+            "number":  "#870087",
+            "literal": "#875f00",
+            "tag":     "#005f87",
+            "attr":    "#008787",
+        },
+    }
+
+    // A theme with bright colors and strong backgrounds for the levels
+    ThemeHighContrast = &Theme{
+        Name: "high-contrast",
+        Levels: map[string]string{
+            "V": "hi-white,on-black",
+            "D": "hi-cyan,on-black",
+            "I": "hi-green,on-black",
+            "W": "bold,hi-yellow,on-black",
+            "E": "bold,hi-red,on-black",
+            "F": "bold,hi-white,on-red",
+        },
+        Badges: map[string]string{
+            "V": "bold,black,on-white",
+            "D": "bold,black,on-hi-cyan",
+            "I": "bold,black,on-hi-green",
+            "W": "bold,black,on-hi-yellow",
+            "E": "bold,hi-white,on-red",
+            "F": "bold,hi-white,on-magenta",
+        },
+        Prefix:    "hi-white",
+        Highlight: "bold,black,on-hi-white",
+        Tags: []string{
+            "bold,hi-red,on-black", "bold,hi-green,on-black", "bold,hi-yellow,on-black",
+            "bold,hi-blue,on-black", "bold,hi-magenta,on-black", "bold,hi-cyan,on-black",
+        },
+        KnownTags: map[string]string{
+            "AndroidRuntime": "bold,hi-cyan,on-black",
+            "DEBUG":          "bold,hi-yellow,on-black",
+        },
+        Syntax: map[string]string{
+            "key":     "bold,hi-cyan,on-black",
+            "string":  "hi-green,on-black",
+            "number":  "hi-magenta,on-black",
+            "literal": "hi-yellow,on-black",
+            "tag":     "bold,hi-cyan,on-black",
+            "attr":    "hi-cyan,on-black",
+        },
+    }
+
+    // The built-in themes by name
+    Themes = map[string]*Theme{
+        ThemeDark.Name:         ThemeDark,
+        ThemeLight.Name:        ThemeLight,
+        ThemeHighContrast.Name: ThemeHighContrast,
+    }
+
+    // The color of the time and PID/TID columns
+    colorPrefix = color.New(color.Bold, color.FgHiBlack)
+
+    // The style of the highlight rules that do not name one
+    highlightStyle = DefaultHighlightStyle
+)
+
+func init() {
+    if err := ApplyTheme(ThemeDark); err != nil {
+        panic(err)
+    }
+}
+
+// Loads a theme from a YAML file
+func LoadThemeFile(fileName string) (*Theme, error) {
+    data, err := os.ReadFile(fileName)
+    if err != nil {
+        return nil, err
+    }
+
+    theme := &Theme{}
+    if err := yaml.Unmarshal(data, theme); err != nil {
+        return nil, fmt.Errorf("invalid theme file (%s): %s", fileName, err)
+    }
+
+    if theme.Name == "" {
+        theme.Name = fileName
+    }
+
+    return theme, nil
+}
+
+// Sets the colors of the output from the theme.
+//
+// Must be called after SetColorDepth and before registering highlight
+// rules and tag colors, as they are parsed with the current theme.
+func ApplyTheme(theme *Theme) error {
+    base := ThemeDark
+    if theme.Base != "" {
+        b, ok := Themes[strings.ToLower(theme.Base)]
+        if !ok {
+            return fmt.Errorf("invalid base theme '%s'", theme.Base)
+        }
+        base = b
+    }
+
+    levels := make([]*color.Color, len(LevelMap))
+    badges := make([]*color.Color, len(LevelMap))
+    for lvl, idx := range LevelMap {
+        var err error
+        if levels[idx], err = ParseStyle(themeStyle(theme.Levels, base.Levels, lvl)); err != nil {
+            return err
+        }
+        if badges[idx], err = ParseStyle(themeStyle(theme.Badges, base.Badges, lvl)); err != nil {
+            return err
+        }
+    }
+
+    prefixStyle := theme.Prefix
+    if prefixStyle == "" {
+        prefixStyle = base.Prefix
+    }
+    prefix, err := ParseStyle(prefixStyle)
+    if err != nil {
+        return err
+    }
+
+    hlStyle := theme.Highlight
+    if hlStyle == "" {
+        hlStyle = base.Highlight
+    }
+    if _, err := ParseStyle(hlStyle); err != nil {
+        return err
+    }
+
+    palette := theme.Tags
+    if len(palette) == 0 {
+        palette = base.Tags
+    }
+    tags := []*color.Color{}
+    for _, s := range palette {
+        c, err := ParseStyle(s)
+        if err != nil {
+            return err
+        }
+        tags = append(tags, c)
+    }
+
+    known := map[string]*color.Color{}
+    for _, m := range []map[string]string{base.KnownTags, theme.KnownTags} {
+        for tag, s := range m {
+            c, err := ParseStyle(s)
+            if err != nil {
+                return err
+            }
+            known[tag] = c
+        }
+    }
+
+    syntax := map[string]*color.Color{}
+    for _, m := range []map[string]string{base.Syntax, theme.Syntax} {
+        for token, s := range m {
+            c, err := ParseStyle(s)
+            if err != nil {
+                return err
+            }
+            syntax[token] = c
+        }
+    }
+
+    colorLevel = levels
+    colorLevelBadge = badges
+    colorPrefix = prefix
+    colorTags = tags
+    knownTags = known
+    highlightStyle = hlStyle
+    colorSyntax = syntax
+
+    return nil
+}
+
+// Gets the style of a level from the theme or from its base
+func themeStyle(styles map[string]string, base map[string]string, level string) string {
+    if s, ok := styles[level]; ok && s != "" {
+        return s
+    }
+    return base[level]
+}
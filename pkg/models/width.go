@@ -0,0 +1,100 @@
+package models
+
+import (
+    "strings"
+
+    "github.com/rivo/uniseg"
+)
+
+// The columns are measured in terminal cells, not in bytes or runes: the wide
+// characters (CJK, most emoji) take two cells, the combining marks none, and a
+// grapheme cluster (e.g. an emoji with modifiers) is never split.
+
+// Gets the width of the text in terminal cells
+func displayWidth(text string) int {
+    return uniseg.StringWidth(text)
+}
+
+// Cuts the text to fit the width, without splitting the grapheme clusters
+func truncateWidth(text string, width int) string {
+    if width <= 0 {
+        return ""
+    }
+
+    sb := strings.Builder{}
+    used := 0
+    state := -1
+    rest := text
+    for len(rest) > 0 {
+        var cluster string
+        var w int
+        cluster, rest, w, state = uniseg.FirstGraphemeClusterInString(rest, state)
+        if used+w > width {
+            break
+        }
+        sb.WriteString(cluster)
+        used += w
+    }
+
+    return sb.String()
+}
+
+// Cuts the text to fit the width, ending with the tail (e.g. "...") when it was cut
+func ellipsisWidth(text string, width int, tail string) string {
+    if displayWidth(text) <= width {
+        return text
+    }
+
+    tw := displayWidth(tail)
+    if width <= tw {
+        return truncateWidth(text, width)
+    }
+
+    return truncateWidth(text, width-tw) + tail
+}
+
+// Adds spaces to the left of the text to fill the width
+func padLeftWidth(text string, width int) string {
+    if n := width - displayWidth(text); n > 0 {
+        return strings.Repeat(" ", n) + text
+    }
+    return text
+}
+
+// Adds spaces to the right of the text to fill the width
+func padRightWidth(text string, width int) string {
+    if n := width - displayWidth(text); n > 0 {
+        return text + strings.Repeat(" ", n)
+    }
+    return text
+}
+
+// Splits the text in lines that fit the width, breaking at the spaces when possible.
+// The space where a line is broken is not kept.
+func splitWidth(text string, width int) []string {
+    parts := []string{}
+    if width <= 0 {
+        return []string{text}
+    }
+
+    for displayWidth(text) > width {
+        line := truncateWidth(text, width)
+        if line == "" {
+            // A cluster wider than the line, take it anyway
+            cluster, _, _, _ := uniseg.FirstGraphemeClusterInString(text, -1)
+            line = cluster
+        }
+
+        // Break at the last space of the second half of the line
+        if len(line) < len(text) && text[len(line)] != ' ' {
+            if k := strings.LastIndex(line, " "); k > 0 && displayWidth(line[:k]) > width/2 {
+                line = line[:k]
+            }
+        }
+
+        parts = append(parts, line)
+        text = strings.TrimPrefix(text[len(line):], " ")
+    }
+
+    return append(parts, text)
+}
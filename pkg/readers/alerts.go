@@ -0,0 +1,241 @@
+package readers
+
+import (
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/filter"
+    "github.com/helviojunior/adbcat/pkg/models"
+    "github.com/helviojunior/adbcat/pkg/sinks"
+)
+
+const (
+    // The alert is sent when the lines after the entry are collected or after this wait
+    alertMaxWait = 3 * time.Second
+)
+
+// Alerts checks the --alert rules and sends an alert to the webhook when an entry
+// matches, with the entries around it and the device. It runs before the stages that
+// drop entries (the collapse and the rate limits), so every entry is checked.
+// The alerts of a rule are debounced.
+type Alerts struct {
+    next     EntryHandler
+    sink     *sinks.AlertSink
+    rules    []*alertRule
+    debounce time.Duration
+    before   int
+    after    int
+
+    device     func() *models.DeviceInfo
+    deviceLast time.Time
+    deviceInfo *models.DeviceInfo
+
+    mutex sync.Mutex
+    // The last entries, for the context before the triggering entry
+    recent []*models.LogcatEntry
+    // The alerts waiting for the entries after the triggering entry
+    pending []*pendingAlert
+
+    stop chan bool
+    wg   sync.WaitGroup
+}
+
+type alertRule struct {
+    filter     *filter.Filter
+    last       time.Time
+    suppressed int
+}
+
+type pendingAlert struct {
+    payload  *sinks.AlertPayload
+    after    int
+    deadline time.Time
+}
+
+// Parses the alert rules, the filter expressions
+func parseAlertRules(rules []string) ([]*filter.Filter, error) {
+    filters := []*filter.Filter{}
+    for _, r := range rules {
+        f, err := filter.Parse(r)
+        if err != nil {
+            return nil, fmt.Errorf("invalid alert rule: %s", err)
+        }
+        filters = append(filters, f)
+    }
+    return filters, nil
+}
+
+// Creates the alert stage with the --alert options, the device (may return nil) is
+// sent with the alerts
+func NewAlerts(opts Options, device func() *models.DeviceInfo, next EntryHandler) (*Alerts, error) {
+    filters, err := parseAlertRules(opts.Alerts)
+    if err != nil {
+        return nil, err
+    }
+
+    sink, err := sinks.NewAlertSink(sinks.AlertOptions{
+        Webhook:  opts.AlertWebhook,
+        Template: opts.AlertTemplate,
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    al := &Alerts{
+        next:     next,
+        sink:     sink,
+        rules:    []*alertRule{},
+        debounce: opts.AlertDebounce,
+        before:   opts.AlertBefore,
+        after:    opts.AlertAfter,
+        device:   device,
+        recent:   []*models.LogcatEntry{},
+        pending:  []*pendingAlert{},
+        stop:     make(chan bool),
+    }
+    for _, f := range filters {
+        al.rules = append(al.rules, &alertRule{filter: f})
+    }
+
+    al.wg.Add(1)
+    go al.flushLoop()
+
+    return al, nil
+}
+
+// Checks if a rule uses the fields of the messages
+func (al *Alerts) UsesFields() bool {
+    for _, r := range al.rules {
+        if r.filter.UsesFields() {
+            return true
+        }
+    }
+    return false
+}
+
+// Handles one entry, it is an EntryHandler
+func (al *Alerts) Handle(entry *models.LogcatEntry) {
+    al.check(entry)
+    al.next(entry)
+}
+
+func (al *Alerts) check(entry *models.LogcatEntry) {
+    al.mutex.Lock()
+    defer al.mutex.Unlock()
+
+    // The entry is context after the pending alerts
+    for _, p := range al.pending {
+        if p.after < al.after {
+            p.payload.Lines = append(p.payload.Lines, entry.ToString())
+            p.after++
+        }
+    }
+
+    now := time.Now()
+    for _, r := range al.rules {
+        if !r.filter.Match(entry) {
+            continue
+        }
+        if !r.last.IsZero() && now.Sub(r.last) < al.debounce {
+            r.suppressed++
+            continue
+        }
+
+        payload := &sinks.AlertPayload{
+            Rule:       r.filter.String(),
+            Time:       now.Format(time.RFC3339),
+            Entry:      entry,
+            Device:     al.getDevice(),
+            Lines:      []string{},
+            Suppressed: r.suppressed,
+        }
+        payload.DeviceName = "unknown device"
+        if payload.Device != nil {
+            payload.DeviceName = payload.Device.Name()
+        }
+        if r.suppressed > 0 {
+            payload.SuppressedText = fmt.Sprintf(" (%d more since the last alert)", r.suppressed)
+        }
+        for _, e := range al.recent {
+            payload.Lines = append(payload.Lines, e.ToString())
+        }
+        payload.Lines = append(payload.Lines, entry.ToString())
+
+        al.pending = append(al.pending, &pendingAlert{
+            payload:  payload,
+            deadline: now.Add(alertMaxWait),
+        })
+        r.last = now
+        r.suppressed = 0
+    }
+
+    if al.before > 0 {
+        al.recent = append(al.recent, entry)
+        if len(al.recent) > al.before {
+            al.recent = al.recent[1:]
+        }
+    }
+
+    al.send(false)
+}
+
+// Sends the pending alerts and stops
+func (al *Alerts) Close() {
+    close(al.stop)
+    al.wg.Wait()
+
+    al.mutex.Lock()
+    al.send(true)
+    al.mutex.Unlock()
+
+    al.sink.Close()
+}
+
+// Sends the alerts that waited too long for the entries after them
+func (al *Alerts) flushLoop() {
+    defer al.wg.Done()
+
+    ticker := time.NewTicker(time.Second)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-al.stop:
+            return
+        case <-ticker.C:
+            al.mutex.Lock()
+            al.send(false)
+            al.mutex.Unlock()
+        }
+    }
+}
+
+// Sends the alerts with all the entries after the triggering one, or waiting for too long.
+// With all, every pending alert is sent. Must be called with the mutex locked.
+func (al *Alerts) send(all bool) {
+    now := time.Now()
+
+    waiting := []*pendingAlert{}
+    for _, p := range al.pending {
+        if !all && p.after < al.after && now.Before(p.deadline) {
+            waiting = append(waiting, p)
+            continue
+        }
+        al.sink.Send(p.payload)
+    }
+    al.pending = waiting
+}
+
+// Gets the device on the first alert, when adb is connected. It is read again
+// every deviceRetryInterval until it works. Must be called with the mutex locked.
+func (al *Alerts) getDevice() *models.DeviceInfo {
+    if al.deviceInfo != nil || al.device == nil || time.Since(al.deviceLast) < deviceRetryInterval {
+        return al.deviceInfo
+    }
+    al.deviceLast = time.Now()
+    if d := al.device(); d != nil && d.Serial != "" {
+        al.deviceInfo = d
+    }
+    return al.deviceInfo
+}
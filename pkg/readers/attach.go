@@ -0,0 +1,150 @@
+package readers
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "os/signal"
+    "strings"
+    "syscall"
+
+    "github.com/helviojunior/adbcat/pkg/log"
+    "github.com/helviojunior/adbcat/pkg/models"
+    "github.com/helviojunior/adbcat/pkg/server"
+    "github.com/helviojunior/adbcat/pkg/sinks"
+)
+
+// Attacher displays the entries streamed by a remote 'adbcat serve' with the
+// outputs of the logcat command (the terminal, the log file and the exporters).
+// The device, the package, the level and the filters are selected by the server.
+type Attacher struct {
+    options Options
+    client  *server.Client
+
+    ctx    context.Context
+    cancel context.CancelFunc
+
+    dispatcher *sinks.Dispatcher
+
+    // The first stage that receives the entries, the last one is DispatchEntry
+    handler   EntryHandler
+    collapser *Collapser
+}
+
+// Creates the attacher of the server at target (host:port or https://host:port)
+func NewAttacher(opts Options, target string, token string, insecure bool) (*Attacher, error) {
+    var err error
+    ctx, cancel := context.WithCancel(context.Background())
+
+    at := &Attacher{
+        options: opts,
+        ctx:     ctx,
+        cancel:  cancel,
+    }
+
+    at.client, err = server.NewClient(target, token, insecure)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := applyDisplayOptions(opts); err != nil {
+        return nil, err
+    }
+
+    if _, ok := models.LevelMap[strings.ToUpper(opts.MinLevel)]; !ok {
+        return nil, fmt.Errorf("invalid level '%s'", opts.MinLevel)
+    }
+
+    // The filters run in the server, check them before connecting
+    if _, err := NewFieldExtractor(false, opts.Filters, nil); err != nil {
+        return nil, err
+    }
+
+    packages := []string{}
+    if opts.PackageName != "" {
+        packages = append(packages, opts.PackageName)
+    }
+    at.dispatcher, _, err = openOutputs(opts, at.device, packages)
+    if err != nil {
+        return nil, err
+    }
+
+    // Chain the processing stages, from the last to the first
+    at.handler = at.DispatchEntry
+    if opts.Collapse {
+        at.collapser = NewCollapser(opts.CollapseWindow, opts.CollapseDigits, true, at.handler)
+        at.handler = at.collapser.Handle
+    }
+    if extractFields(opts) {
+        // The server sends the fields, they are extracted here when it did not
+        fields, _ := NewFieldExtractor(true, nil, at.handler)
+        at.handler = fields.Handle
+    }
+
+    return at, nil
+}
+
+// Displays the entries until Ctrl+C, or until the server refuses the stream
+func (at *Attacher) Run() error {
+    defer at.cancel()
+    defer at.dispatcher.Close()
+
+    c := make(chan os.Signal, 1)
+    signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+    go func() {
+        <-c
+        at.cancel()
+    }()
+
+    if devices, err := at.client.Devices(at.ctx); err == nil && at.options.DeviceSerial == "" && len(devices) > 1 {
+        serials := []string{}
+        for _, d := range devices {
+            serials = append(serials, d.Serial)
+        }
+        log.Warn("The server has several devices, showing the entries of all of them. Use -s to choose one.", "devices", strings.Join(serials, ", "))
+    }
+
+    err := at.client.Stream(at.ctx, server.StreamOptions{
+        Serial:   at.options.DeviceSerial,
+        Package:  at.options.PackageName,
+        MinLevel: at.options.MinLevel,
+        Filters:  at.options.Filters,
+        Tail:     at.options.AttachTail,
+    }, func(ev *server.StreamEntry) {
+        at.handler(ev.LogcatEntry)
+    })
+
+    if at.collapser != nil {
+        at.collapser.Close()
+    }
+    if cerr := at.dispatcher.Close(); cerr != nil && err == nil {
+        err = cerr
+    }
+
+    return err
+}
+
+// Stops reading the stream, Run returns after everything is written
+func (at *Attacher) Stop() {
+    at.cancel()
+}
+
+func (at *Attacher) DispatchEntry(entry *models.LogcatEntry) {
+    at.dispatcher.Handle(entry)
+}
+
+// Gets the device of the entries from the server, nil when unknown
+func (at *Attacher) device() *models.DeviceInfo {
+    devices, err := at.client.Devices(at.ctx)
+    if err != nil {
+        log.Debug("error getting the devices of the server", "err", err)
+        return nil
+    }
+
+    for _, d := range devices {
+        if d != nil && (at.options.DeviceSerial == "" || d.Serial == at.options.DeviceSerial) {
+            return d
+        }
+    }
+    return nil
+}
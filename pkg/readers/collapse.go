@@ -0,0 +1,248 @@
+package readers
+
+import (
+    "regexp"
+    "sync"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+var (
+    // Numbers are replaced by this regex when comparing normalized messages
+    reDigits = regexp.MustCompile(`\d+`)
+)
+
+const (
+    // A pending group of consecutive messages is written after this idle time (live only)
+    collapseIdle = 200 * time.Millisecond
+)
+
+// EntryHandler receives the merged entries, it is how the processing stages are chained
+type EntryHandler func(entry *models.LogcatEntry)
+
+// Collapser folds repeated messages (same tag and message) into one entry
+// with the number of repetitions.
+//
+// Without a window, consecutive repeated messages are folded into one line,
+// written when another message comes (or, live, after collapseIdle without entries).
+// With a window, the first message is written right away and the repetitions
+// seen within the window are written as one line when the window ends. The
+// window uses the log time, advanced by the wall clock while no entries come
+// when live.
+type Collapser struct {
+    next            EntryHandler
+    window          time.Duration
+    normalizeDigits bool
+
+    mutex   sync.Mutex
+    pending *collapseGroup
+    groups  map[string]*collapseGroup
+
+    // The log time of the latest entry and when it was seen (wall clock)
+    clock   time.Time
+    clockAt time.Time
+    // The log time of the last check of the windows
+    expired time.Time
+
+    stop chan bool
+    wg   sync.WaitGroup
+}
+
+type collapseGroup struct {
+    key   string
+    entry *models.LogcatEntry
+    count int // The entries not written yet
+
+    // The log time of the first and the last entry not written yet
+    first time.Time
+    last  time.Time
+
+    // The log time of the entry that opened the window
+    start time.Time
+
+    // When the group was last updated (wall clock)
+    updated time.Time
+}
+
+// Creates a collapser that writes the entries to next. When live, the pending
+// entries are also written by the wall clock, while no entries come; otherwise
+// (saved logs) only the log time and Close write them.
+func NewCollapser(window time.Duration, normalizeDigits bool, live bool, next EntryHandler) *Collapser {
+    c := &Collapser{
+        next:            next,
+        window:          window,
+        normalizeDigits: normalizeDigits,
+        groups:          map[string]*collapseGroup{},
+        stop:            make(chan bool),
+    }
+
+    if live {
+        c.wg.Add(1)
+        go c.flushLoop()
+    }
+
+    return c
+}
+
+// Handles one entry, it is an EntryHandler
+func (c *Collapser) Handle(entry *models.LogcatEntry) {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    now := time.Now()
+    key := c.key(entry)
+    ts, err := entry.Timestamp()
+    if err != nil {
+        ts = c.logNow(now)
+    }
+    if ts.After(c.clock) {
+        c.clock = ts
+    }
+    c.clockAt = now
+
+    if c.window <= 0 {
+        if c.pending != nil && c.pending.key == key {
+            c.pending.count++
+            c.pending.last = ts
+            c.pending.updated = now
+            return
+        }
+
+        c.flushPending()
+        c.pending = &collapseGroup{
+            key:     key,
+            entry:   entry,
+            count:   1,
+            first:   ts,
+            last:    ts,
+            updated: now,
+        }
+        return
+    }
+
+    // The windows ended by the log time, checked at most every collapseIdle/2 of log time
+    if c.clock.Sub(c.expired) >= collapseIdle/2 {
+        c.expireGroups(c.clock)
+    }
+
+    if g, ok := c.groups[key]; ok {
+        if ts.Sub(g.start) <= c.window {
+            if g.count == 0 {
+                g.first = ts
+            }
+            g.count++
+            g.last = ts
+            g.updated = now
+            g.entry = entry
+            return
+        }
+        c.flushGroup(g)
+    }
+
+    // The first entry is written now, the group holds the repetitions
+    c.groups[key] = &collapseGroup{
+        key:     key,
+        entry:   entry,
+        start:   ts,
+        updated: now,
+    }
+    c.next(entry)
+}
+
+// Writes everything pending and stops the flush loop
+func (c *Collapser) Close() {
+    close(c.stop)
+    c.wg.Wait()
+
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    c.flushPending()
+    for _, g := range c.groups {
+        c.flushGroup(g)
+    }
+}
+
+func (c *Collapser) flushLoop() {
+    defer c.wg.Done()
+
+    ticker := time.NewTicker(collapseIdle / 2)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-c.stop:
+            return
+        case now := <-ticker.C:
+            c.mutex.Lock()
+            if p := c.pending; p != nil && now.Sub(p.updated) >= collapseIdle {
+                c.flushPending()
+            }
+            c.expireGroups(c.logNow(now))
+            c.mutex.Unlock()
+        }
+    }
+}
+
+// Gets the log time now: the time of the latest entry plus the time passed since
+// it was seen, must be called with the mutex locked
+func (c *Collapser) logNow(now time.Time) time.Time {
+    if c.clock.IsZero() {
+        return now
+    }
+
+    return c.clock.Add(now.Sub(c.clockAt))
+}
+
+// Writes the window groups ended at the log time clock, must be called with the mutex locked
+func (c *Collapser) expireGroups(clock time.Time) {
+    for _, g := range c.groups {
+        if clock.Sub(g.start) > c.window {
+            c.flushGroup(g)
+        }
+    }
+    c.expired = clock
+}
+
+// Writes the pending consecutive group, must be called with the mutex locked
+func (c *Collapser) flushPending() {
+    if c.pending == nil {
+        return
+    }
+
+    c.next(c.pending.folded())
+    c.pending = nil
+}
+
+// Writes the repetitions of a window group, must be called with the mutex locked
+func (c *Collapser) flushGroup(g *collapseGroup) {
+    delete(c.groups, g.key)
+
+    // The first entry was already written
+    if g.count > 0 {
+        c.next(g.folded())
+    }
+}
+
+// Gets the entry of the group with the repetitions
+func (g *collapseGroup) folded() *models.LogcatEntry {
+    if g.count <= 1 {
+        return g.entry
+    }
+
+    e := *g.entry
+    e.Count = g.count
+    e.CountSpan = g.last.Sub(g.first).Seconds()
+
+    return &e
+}
+
+func (c *Collapser) key(entry *models.LogcatEntry) string {
+    msg := entry.Message
+    if c.normalizeDigits {
+        msg = reDigits.ReplaceAllString(msg, "#")
+    }
+
+    return entry.Tag + "\x00" + msg
+}
@@ -0,0 +1,164 @@
+package readers
+
+import (
+    "fmt"
+    "slices"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+func TestCollapser(t *testing.T) {
+    type input struct {
+        time    string
+        tag     string
+        message string
+    }
+
+    tests := []struct {
+        name   string
+        window time.Duration
+        digits bool
+        input  []input
+        want   []string
+    }{
+        {
+            name: "consecutive",
+            input: []input{
+                {"10:00:00.000", "A", "hello"},
+                {"10:00:01.000", "A", "hello"},
+                {"10:00:02.500", "A", "hello"},
+                {"10:00:03.000", "B", "hello"},
+                {"10:00:04.000", "A", "hello"},
+            },
+            want: []string{"A hello x3 2.5s", "B hello", "A hello"},
+        },
+        {
+            name: "consecutive with other tag",
+            input: []input{
+                {"10:00:00.000", "A", "hello"},
+                {"10:00:00.100", "A", "bye"},
+                {"10:00:00.200", "A", "hello"},
+            },
+            want: []string{"A hello", "A bye", "A hello"},
+        },
+        {
+            name:   "consecutive ignoring digits",
+            digits: true,
+            input: []input{
+                {"10:00:00.000", "A", "took 10ms"},
+                {"10:00:01.000", "A", "took 12ms"},
+            },
+            want: []string{"A took 10ms x2 1s"},
+        },
+        {
+            name: "consecutive with digits",
+            input: []input{
+                {"10:00:00.000", "A", "took 10ms"},
+                {"10:00:01.000", "A", "took 12ms"},
+            },
+            want: []string{"A took 10ms", "A took 12ms"},
+        },
+        {
+            name:   "window counts only the repetitions",
+            window: 10 * time.Second,
+            input: []input{
+                {"10:00:00.000", "A", "hello"},
+                {"10:00:01.000", "B", "other"},
+                {"10:00:02.000", "A", "hello"},
+                {"10:00:05.000", "A", "hello"},
+            },
+            want: []string{"A hello", "B other", "A hello x2 3s"},
+        },
+        {
+            name:   "window with one repetition",
+            window: 10 * time.Second,
+            input: []input{
+                {"10:00:00.000", "A", "hello"},
+                {"10:00:01.000", "A", "hello"},
+            },
+            want: []string{"A hello", "A hello"},
+        },
+        {
+            name:   "window ended",
+            window: 10 * time.Second,
+            input: []input{
+                {"10:00:00.000", "A", "hello"},
+                {"10:00:02.000", "A", "hello"},
+                {"10:00:04.000", "A", "hello"},
+                {"10:00:20.000", "A", "hello"},
+                {"10:00:21.000", "A", "hello"},
+            },
+            want: []string{"A hello", "A hello x2 2s", "A hello", "A hello"},
+        },
+        {
+            name:   "window ended by other messages",
+            window: 10 * time.Second,
+            input: []input{
+                {"10:00:00.000", "A", "hello"},
+                {"10:00:01.000", "A", "hello"},
+                {"10:00:02.000", "A", "hello"},
+                {"10:00:15.000", "B", "other"},
+            },
+            want: []string{"A hello", "A hello x2 1s", "B other"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := []string{}
+            c := NewCollapser(tt.window, tt.digits, false, func(e *models.LogcatEntry) {
+                s := e.Tag + " " + e.Message
+                if e.Count > 0 {
+                    s += fmt.Sprintf(" x%d %gs", e.Count, e.CountSpan)
+                }
+                got = append(got, s)
+            })
+
+            for _, in := range tt.input {
+                c.Handle(&models.LogcatEntry{
+                    Date:    "01-02",
+                    Time:    in.time,
+                    Level:   "I",
+                    Tag:     in.tag,
+                    Message: in.message,
+                })
+            }
+            c.Close()
+
+            if !slices.Equal(got, tt.want) {
+                t.Errorf("got %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+// A live burst longer than a second is folded into one line
+func TestCollapserLiveBurst(t *testing.T) {
+    mutex := sync.Mutex{}
+    got := []string{}
+    c := NewCollapser(0, false, true, func(e *models.LogcatEntry) {
+        mutex.Lock()
+        defer mutex.Unlock()
+        got = append(got, fmt.Sprintf("%s x%d", e.Message, e.Count))
+    })
+
+    start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.Local)
+    for i := 0; i < 12; i++ {
+        ts := start.Add(time.Duration(i) * 100 * time.Millisecond)
+        c.Handle(&models.LogcatEntry{
+            Date:    ts.Format("01-02"),
+            Time:    ts.Format("15:04:05.000"),
+            Tag:     "A",
+            Message: "hello",
+        })
+        time.Sleep(100 * time.Millisecond)
+    }
+    c.Close()
+
+    if !slices.Equal(got, []string{"hello x12"}) {
+        t.Errorf("got %q, want one line", got)
+    }
+}
@@ -0,0 +1,39 @@
+package readers
+
+import (
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// Sets how the entries are displayed (highlights, tag colors, wrapping, pretty
+// printing and the line template) from the options
+func applyDisplayOptions(opts Options) error {
+    // Highlight the explicit rules first, then the terms being included
+    for _, r := range opts.HighlightRules {
+        rule, err := models.ParseHighlightRule(r)
+        if err != nil {
+            return err
+        }
+        models.AddHighlightRule(rule)
+    }
+
+    for _, term := range opts.IncludeFilterList {
+        if err := models.AddHighlightTerm(term); err != nil {
+            return err
+        }
+    }
+
+    for _, tc := range opts.TagColors {
+        if err := models.ParseTagColor(tc); err != nil {
+            return err
+        }
+    }
+
+    models.SetWrap(opts.Wrap)
+    models.SetPrettyPrint(opts.PrettyJSON, opts.PrettyXML)
+
+    if err := models.SetLineTemplate(opts.Template); err != nil {
+        return err
+    }
+
+    return nil
+}
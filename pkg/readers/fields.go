@@ -0,0 +1,128 @@
+package readers
+
+import (
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+
+    "github.com/helviojunior/adbcat/pkg/filter"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+var (
+    // A key=value pair, the value may be quoted
+    reKeyValue = regexp.MustCompile(`(?:^|[\s,;({\[])([A-Za-z_][\w.\-]*)=("(?:[^"\\]|\\.)*"|'[^']*'|[^\s,;)}\]]*)`)
+)
+
+// FieldExtractor sets the fields of the entries (see models.LogcatEntry.Fields) and
+// drops the entries that do not match the --filter expressions
+type FieldExtractor struct {
+    next    EntryHandler
+    extract bool
+    filters []*filter.Filter
+}
+
+// Creates the extraction stage. The fields are extracted when extract is set or
+// when a filter uses them.
+func NewFieldExtractor(extract bool, filters []string, next EntryHandler) (*FieldExtractor, error) {
+    fe := &FieldExtractor{
+        next:    next,
+        extract: extract,
+        filters: []*filter.Filter{},
+    }
+
+    for _, s := range filters {
+        f, err := filter.Parse(s)
+        if err != nil {
+            return nil, err
+        }
+        if f.UsesFields() {
+            fe.extract = true
+        }
+        fe.filters = append(fe.filters, f)
+    }
+
+    return fe, nil
+}
+
+// Checks if the fields of the messages are needed by the options: --fields or
+// the line template (the filters are checked by NewFieldExtractor)
+func extractFields(opts Options) bool {
+    return opts.Fields || models.LineTemplateUsesFields()
+}
+
+// Handles one entry, it is an EntryHandler
+func (fe *FieldExtractor) Handle(entry *models.LogcatEntry) {
+    if fe.extract && entry.Fields == nil {
+        entry.Fields = ExtractFields(entry.Message)
+    }
+
+    for _, f := range fe.filters {
+        if !f.Match(entry) {
+            return
+        }
+    }
+
+    fe.next(entry)
+}
+
+// Gets the key=value pairs and the fields of the JSON objects of a message.
+// The nested JSON fields are named with dots (e.g. user.id, items.0.name).
+// Returns nil when there is no field.
+func ExtractFields(message string) map[string]string {
+    fields := map[string]string{}
+
+    for _, m := range reKeyValue.FindAllStringSubmatch(message, -1) {
+        value := m[2]
+        if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+            if s, err := strconv.Unquote(value); err == nil && value[0] == '"' {
+                value = s
+            }else{
+                value = value[1 : len(value)-1]
+            }
+        }
+        fields[m[1]] = value
+    }
+
+    for i := 0; i < len(message); i++ {
+        if message[i] != '{' {
+            continue
+        }
+
+        dec := json.NewDecoder(strings.NewReader(message[i:]))
+        dec.UseNumber()
+        obj := map[string]interface{}{}
+        if err := dec.Decode(&obj); err != nil {
+            continue
+        }
+
+        flattenFields(fields, "", obj)
+        i += int(dec.InputOffset()) - 1
+    }
+
+    if len(fields) == 0 {
+        return nil
+    }
+
+    return fields
+}
+
+// Adds the JSON values to the fields, the names of the nested values are joined with dots
+func flattenFields(fields map[string]string, prefix string, value interface{}) {
+    switch v := value.(type) {
+    case map[string]interface{}:
+        for k, item := range v {
+            flattenFields(fields, prefix+k+".", item)
+        }
+    case []interface{}:
+        for i, item := range v {
+            flattenFields(fields, fmt.Sprintf("%s%d.", prefix, i), item)
+        }
+    case nil:
+        fields[strings.TrimSuffix(prefix, ".")] = "null"
+    default:
+        fields[strings.TrimSuffix(prefix, ".")] = fmt.Sprint(v)
+    }
+}
@@ -2,12 +2,31 @@ package readers
 
 import (
     "bufio"
+    "encoding/json"
+    "io"
     "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
     "strings"
+
+    "github.com/helviojunior/adbcat/internal/ascii"
+    "github.com/helviojunior/adbcat/pkg/adb"
+    "github.com/helviojunior/adbcat/pkg/models"
 )
 
 // Read from a file.
 func ReadAllLines(fileName string, outList *[]string) error {
+    return readLines(fileName, outList, true)
+}
+
+// Read from a file keeping the case of the lines.
+// Lines starting with '#' are taken as comments and ignored.
+func ReadAllRawLines(fileName string, outList *[]string) error {
+    return readLines(fileName, outList, false)
+}
+
+func readLines(fileName string, outList *[]string, lower bool) error {
 
     var file *os.File
     var err error
@@ -28,15 +47,177 @@ func ReadAllLines(fileName string, outList *[]string) error {
             lastLine = true
         }
 
-        line = strings.Trim(strings.Replace(strings.Replace(strings.ToLower(line), "\n", "", -1), "\r", "", -1), " ")
-        if line != "" {
+        if lower {
+            line = strings.ToLower(line)
+        }
+        line = strings.Trim(strings.Replace(strings.Replace(line, "\n", "", -1), "\r", "", -1), " ")
+        if line != "" && (lower || line[0:1] != "#") {
             *outList = append(*outList, line)
         }
 
         if lastLine {
+            if err != io.EOF {
+                return err
+            }
             break
         }
     }
 
     return nil
 }
+
+var (
+    // A line written by adbcat (text or ANSI log file): time, pid-tid, tag, level and message
+    reAdbcatLine = regexp.MustCompile(`^(\d{2}:\d{2}:\d{2}\.\d{3})\s+(\d+)-(\d*)\s+(.*?) ([VDIWEF]) (.*)$`)
+    // A continuation line written by adbcat: indentation, level and message
+    reAdbcatContinuation = regexp.MustCompile(`^\s+([VDIWEF]) (.*)$`)
+    // The suffix of the collapsed entries
+    reRepeatSuffix = regexp.MustCompile(` \(repeated ×(\d+) over ([\d.]+)s\)$`)
+)
+
+// Reads a saved log file and sends the entries to handler.
+//
+// The file may have the raw output of 'adb logcat' (threadtime format),
+// the text, ANSI or JSON log files written by adbcat, be an Android Studio
+// .logcat file or a Perfetto trace with the android_log data source.
+// Returns the number of lines that could not be parsed.
+func ReadLogFile(fileName string, handler EntryHandler) (int, error) {
+    file, err := os.Open(fileName)
+    if err != nil {
+        return 0, err
+    }
+    defer file.Close()
+
+    reader := bufio.NewReader(file)
+    if isStudioFile(fileName, reader) {
+        entries, _, err := models.ReadStudioLogcat(reader)
+        if err != nil {
+            return 0, err
+        }
+        for _, entry := range entries {
+            restoreRepeat(entry)
+            handler(entry)
+        }
+        return 0, nil
+    }
+
+    if isPerfettoTrace(fileName, reader) {
+        entries, err := models.ReadPerfettoTrace(reader)
+        if err != nil {
+            return 0, err
+        }
+        for _, entry := range entries {
+            handler(entry)
+        }
+        return 0, nil
+    }
+
+    unparsed := 0
+    merger := newLineMerger(handler)
+    var current *models.LogcatEntry
+
+    // Entries of the adbcat files are complete (or continued) when read,
+    // the raw logcat lines go through the merger
+    flush := func() {
+        if current != nil {
+            restoreRepeat(current)
+            handler(current)
+            current = nil
+        }
+    }
+
+    scanner := bufio.NewScanner(reader)
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+    for scanner.Scan() {
+        line := strings.TrimRight(ascii.ScapeAnsi(scanner.Text()), "\r")
+        if strings.TrimSpace(line) == "" {
+            continue
+        }
+
+        if strings.HasPrefix(line, "{") {
+            entry := &models.LogcatEntry{}
+            if err := json.Unmarshal([]byte(line), entry); err == nil {
+                merger.Flush()
+                flush()
+                handler(entry)
+                continue
+            }
+        }
+
+        if entry, err := adb.ParseLogcatLine(line); err == nil {
+            flush()
+            merger.Add(entry)
+            continue
+        }
+
+        if m := reAdbcatLine.FindStringSubmatch(line); m != nil {
+            merger.Flush()
+            flush()
+            current = &models.LogcatEntry{
+                Time:    m[1],
+                PID:     m[2],
+                TID:     m[3],
+                Tag:     strings.TrimSpace(m[4]),
+                Level:   m[5],
+                Message: strings.TrimRight(m[6], " "),
+            }
+            continue
+        }
+
+        if m := reAdbcatContinuation.FindStringSubmatch(line); m != nil && current != nil && m[1] == current.Level {
+            current.Message += "\n" + strings.TrimRight(m[2], " ")
+            continue
+        }
+
+        unparsed++
+    }
+
+    merger.Flush()
+    flush()
+
+    return unparsed, scanner.Err()
+}
+
+// Checks if the file is an Android Studio .logcat file, by the extension or by the
+// metadata object at its start (the JSON log files of adbcat have one entry per line)
+func isStudioFile(fileName string, reader *bufio.Reader) bool {
+    if strings.EqualFold(filepath.Ext(fileName), ".logcat") {
+        return true
+    }
+
+    head, _ := reader.Peek(512)
+    text := strings.TrimSpace(string(head))
+    return strings.HasPrefix(text, "{") && strings.Contains(text, `"metadata"`)
+}
+
+// Checks if the file is a Perfetto trace, by the extension or by its start: the
+// first packet of the trace (field 1, length-delimited) followed by binary data
+func isPerfettoTrace(fileName string, reader *bufio.Reader) bool {
+    switch strings.ToLower(filepath.Ext(fileName)) {
+    case ".perfetto-trace", ".pftrace":
+        return true
+    }
+
+    head, _ := reader.Peek(64)
+    if len(head) < 2 || head[0] != 0x0a {
+        return false
+    }
+    for _, c := range head {
+        if c < 0x20 && c != '\n' && c != '\r' && c != '\t' {
+            return true
+        }
+    }
+    return false
+}
+
+// Restores the repetitions of an entry from the suffix written by adbcat
+func restoreRepeat(entry *models.LogcatEntry) {
+    m := reRepeatSuffix.FindStringSubmatch(entry.Message)
+    if m == nil {
+        return
+    }
+
+    entry.Count, _ = strconv.Atoi(m[1])
+    entry.CountSpan, _ = strconv.ParseFloat(m[2], 64)
+    entry.Message = strings.TrimSuffix(entry.Message, m[0])
+}
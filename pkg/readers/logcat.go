@@ -6,20 +6,28 @@ import (
     "fmt"
 
     "bufio"
+    "io"
+    "net/http"
     "os"
     "os/exec"
     "os/signal"
     "sync"
+    "sync/atomic"
     "syscall"
     "time"
     "slices"
+    "regexp"
 
-    "github.com/fatih/color"
     "github.com/helviojunior/adbcat/pkg/models"
     "github.com/helviojunior/adbcat/pkg/log"
     "github.com/helviojunior/adbcat/pkg/adb"
+    "github.com/helviojunior/adbcat/pkg/metrics"
+    "github.com/helviojunior/adbcat/pkg/sinks"
 )
 
+// The log time at the start of a logcat line, like '01-02 15:04:05.000'
+var reLineTime = regexp.MustCompile(`^\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}`)
+
 type LogcatRunner struct {
     
     ADBClient *adb.Client
@@ -31,13 +39,45 @@ type LogcatRunner struct {
     ctx    context.Context
     cancel context.CancelFunc
 
-    logFile *os.File
+    // The sinks of the entries, the log file is kept for its size
+    dispatcher *sinks.Dispatcher
+    logFile    *sinks.FileSink
+
+    // Cleared by Stop, only stops the reading, the entries already read are still written
+    running atomic.Bool
+
+    // The first stage that receives the merged entries, the last one is DispatchEntry
+    handler   EntryHandler
+    collapser *Collapser
+    limiter   *RateLimiter
+    alerts    *Alerts
+    stats     *Stats
+    values    *ValueMetrics
+
+    // The Prometheus metrics and their server, nil without --metrics-addr
+    metrics       *Metrics
+    metricsServer *http.Server
 
-    running bool
+    // Where the entries end up, DispatchEntry by default
+    output EntryHandler
 
+    mutex        sync.Mutex
+    pids         []string          // The PIDs of the wanted packages
+    processNames map[string]string // The process names by PID
 }
 
+// Creates a runner that writes the entries to the sinks of the options (the
+// terminal, the log file and the exporters)
 func NewRunner(opts Options) (*LogcatRunner, error) {
+    return newRunner(opts, nil)
+}
+
+// Creates a runner that sends the entries to the output only, no sink is opened
+func NewRunnerWithOutput(opts Options, output EntryHandler) (*LogcatRunner, error) {
+    return newRunner(opts, output)
+}
+
+func newRunner(opts Options, output EntryHandler) (*LogcatRunner, error) {
     var err error
     ctx, cancel := context.WithCancel(context.Background())
 
@@ -58,10 +98,12 @@ func NewRunner(opts Options) (*LogcatRunner, error) {
         cancel:     cancel,
         options:    opts,
         Logcat: &adb.LogcatOptions{},
-        logFile: nil,
-        running: true,
+        pids: []string{},
+        processNames: map[string]string{},
     }
 
+    runner.running.Store(true)
+
     runner.ADBClient, err = adb.NewClient(opts.AdbBinPath, connectionStr)
     if err != nil {
         return nil, err
@@ -82,6 +124,10 @@ func NewRunner(opts Options) (*LogcatRunner, error) {
         }
     }
 
+    if err := applyDisplayOptions(opts); err != nil {
+        return nil, err
+    }
+
     minLevel := strings.ToUpper(opts.MinLevel)
     if _, ok := models.LevelMap[minLevel]; !ok {
         return nil, fmt.Errorf("invalid level '%s'", minLevel)
@@ -89,53 +135,200 @@ func NewRunner(opts Options) (*LogcatRunner, error) {
     runner.Logcat.MinLevel = minLevel
 
 
-    if opts.LogFile != "" {
-        runner.logFile, err = os.OpenFile(opts.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+    runner.output = output
+    if output == nil {
+        runner.dispatcher, runner.logFile, err = openOutputs(opts, runner.DeviceInfo, runner.Logcat.Packages)
         if err != nil {
             return nil, err
         }
+        runner.output = runner.DispatchEntry
+    }else{
+        runner.dispatcher = sinks.NewDispatcher()
     }
 
+    // The outputs are open (the log file and the goroutines of the sinks), close them when a stage fails
+    ready := false
+    defer func() {
+        if !ready {
+            runner.closeStages()
+        }
+    }()
+
+    // Chain the processing stages, from the last to the first
+    runner.handler = func(entry *models.LogcatEntry) {
+        runner.output(entry)
+    }
+    if len(opts.RateLimits) > 0 || opts.MaxRate != "" || len(opts.Samples) > 0 {
+        runner.limiter, err = NewRateLimiter(opts.RateLimits, opts.MaxRate, opts.Samples, runner.handler)
+        if err != nil {
+            return nil, err
+        }
+        runner.handler = runner.limiter.Handle
+    }
+    if opts.Collapse {
+        runner.collapser = NewCollapser(opts.CollapseWindow, opts.CollapseDigits, true, runner.handler)
+        runner.handler = runner.collapser.Handle
+    }
+    if opts.MetricsAddr != "" {
+        runner.metrics = NewMetrics(opts.MetricsMaxTags, runner.DeviceInfo, runner.handler)
+        runner.handler = runner.metrics.Handle
+
+        // Before the metric rules, so they can not take the names of the metrics of adbcat
+        runner.registerMetrics()
+    }
+    if len(opts.ValueMetrics) > 0 {
+        var registry *metrics.Registry
+        device := func() string { return "" }
+        if runner.metrics != nil {
+            registry = runner.metrics.Registry()
+            device = runner.metrics.Device
+        }
+        runner.values, err = NewValueMetrics(opts.ValueMetrics, registry, device, opts.MetricsMaxTags, runner.handler)
+        if err != nil {
+            return nil, err
+        }
+        runner.handler = runner.values.Handle
+    }
+    runner.stats = NewStats(runner.handler)
+    runner.handler = runner.stats.Handle
+
+    // The alerts see the entries before they are collapsed or dropped by the rate limits
+    extract := extractFields(opts)
+    if len(opts.Alerts) > 0 {
+        runner.alerts, err = NewAlerts(opts, runner.DeviceInfo, runner.handler)
+        if err != nil {
+            return nil, err
+        }
+        runner.handler = runner.alerts.Handle
+        extract = extract || runner.alerts.UsesFields()
+    }
+
+    if extract || len(opts.Filters) > 0 {
+        fields, err := NewFieldExtractor(extract, opts.Filters, runner.handler)
+        if err != nil {
+            return nil, err
+        }
+        runner.handler = fields.Handle
+    }
+
+    if runner.metrics != nil {
+        runner.metricsServer, err = metrics.Serve(opts.MetricsAddr, runner.metrics.Registry())
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    ready = true
     return &runner, nil
 }
 
-func (run LogcatRunner) Run() {
-    defer run.cancel()
+// Stops the stages with goroutines and closes the outputs, when NewRunner fails
+func (run *LogcatRunner) closeStages() {
+    if run.alerts != nil {
+        run.alerts.Close()
+    }
+    if run.collapser != nil {
+        run.collapser.Close()
+    }
+    if run.limiter != nil {
+        run.limiter.Close()
+    }
+    run.dispatcher.Close()
+}
 
-    pids := []string{}
+// Adds the metrics of the other stages, they are read when the metrics are scraped
+func (run *LogcatRunner) registerMetrics() {
+    registry := run.metrics.Registry()
+
+    registry.CounterFunc("adbcat_parse_failures_total",
+        "Lines of logcat that could not be parsed.", []string{"device"},
+        func() []metrics.Sample {
+            return []metrics.Sample{{
+                Labels: []string{run.metrics.Device()},
+                Value:  float64(run.stats.UnparsedLines()),
+            }}
+        })
+
+    registry.CounterFunc("adbcat_dropped_entries_total",
+        "Entries dropped by the rate limits and the sampling.", []string{"device", "reason"},
+        func() []metrics.Sample {
+            samples := []metrics.Sample{}
+            for _, reason := range []string{DropRateLimit, DropSample} {
+                dropped := 0
+                if run.limiter != nil {
+                    dropped = run.limiter.DroppedBy(reason)
+                }
+                samples = append(samples, metrics.Sample{
+                    Labels: []string{run.metrics.Device(), reason},
+                    Value:  float64(dropped),
+                })
+            }
+            return samples
+        })
+
+    // The counters of the sinks
+    sinkMetric := func(name string, help string, value func(sinks.SinkStats) int64) {
+        registry.CounterFunc(name, help, []string{"device", "sink"}, func() []metrics.Sample {
+            samples := []metrics.Sample{}
+            for _, st := range run.dispatcher.Stats() {
+                samples = append(samples, metrics.Sample{
+                    Labels: []string{run.metrics.Device(), st.Name},
+                    Value:  float64(value(st)),
+                })
+            }
+            return samples
+        })
+    }
+    sinkMetric("adbcat_sink_written_entries_total", "Entries written by the sinks (terminal, file, exporters).",
+        func(st sinks.SinkStats) int64 { return st.Written })
+    sinkMetric("adbcat_sink_dropped_entries_total", "Entries dropped because the queue of the sink was full.",
+        func(st sinks.SinkStats) int64 { return st.Dropped })
+    sinkMetric("adbcat_sink_errors_total", "Errors of the sinks, like failed requests of the exporters.",
+        func(st sinks.SinkStats) int64 { return st.Errors })
+}
 
-    _, err := run.ADBClient.ListDevices()
+// Reads the device info from adb, nil when it fails
+func (run *LogcatRunner) DeviceInfo() *models.DeviceInfo {
+    device, err := run.ADBClient.GetDeviceInfo()
     if err != nil {
-        log.Errorf("%s", err)
-        return
+        log.Debug("error getting the device info", "err", err)
+        return nil
     }
+    return device
+}
 
-    err = run.ADBClient.CheckConn()
+func (run *LogcatRunner) Run() {
+    defer run.cancel()
+    defer run.dispatcher.Close()
+
+    _, err := run.ADBClient.ListDevices()
     if err != nil {
         log.Errorf("%s", err)
         return
     }
 
-    cmd := exec.CommandContext(run.ctx, run.ADBClient.BaseCmdLogcat[0], run.ADBClient.BaseCmdLogcat[1:]...)
-
-    // Capture the output of the logcat command
-    stdout, err := cmd.StdoutPipe()
+    err = run.ADBClient.CheckConn()
     if err != nil {
         log.Errorf("%s", err)
         return
     }
 
+    // Load the process names before the first lines arrive
+    run.refreshProcesses()
+
     // Start logcat
-    if err := cmd.Start(); err != nil {
+    cmd, stdout, err := run.startLogcat(run.options.Since)
+    if err != nil {
         log.Errorf("%s", err)
         os.Exit(1)
     }
 
     if len(run.Logcat.Packages) > 0 {
-        pids = append(pids, "invalid")  // Create an pid to ignore all other packeges logs
+        run.setPids([]string{"invalid"})  // Create an pid to ignore all other packeges logs
     }
 
-    // Create a go function that every two seconds checks for the PIDs of the wanted packages
+    // Create a go function that every two seconds refreshes the process names
+    // and checks for the PIDs of the wanted packages
     stopChanPidWatchDog := make(chan bool)
     wgPidWatchDog := new(sync.WaitGroup)
     wgPidWatchDog.Add(1)
@@ -155,15 +348,9 @@ func (run LogcatRunner) Run() {
                     nPids := []string{"invalid"}
                     for !found {
 
+                        run.refreshProcesses()
                         for _, slug := range run.Logcat.Packages {
-                            pid, err := run.ADBClient.GetPID(slug)
-                            if err != nil {
-                                if message {
-                                    log.Warn("No processes found for the specified packages. Waiting 30 seconds for them to appear...")
-                                    message = false
-                                }
-                                found = false
-                            }else{
+                            for _, pid := range run.packagePids(slug) {
                                 found = true
                                 // Add the pid to the slice if it's not already there
                                 if !slices.Contains(nPids, pid) {
@@ -173,6 +360,11 @@ func (run LogcatRunner) Run() {
                         }
 
                         if !found {
+                            if message {
+                                log.Warn("No processes found for the specified packages. Waiting 30 seconds for them to appear...")
+                                message = false
+                            }
+
                             time.Sleep(300 * time.Millisecond)
 
                             if time.Since(start) >= 30*time.Second {
@@ -184,7 +376,9 @@ func (run LogcatRunner) Run() {
                         log.Error("No processes found for the specified packages.")
                         os.Exit(2)
                     }
-                    pids = nPids
+                    run.setPids(nPids)
+                } else {
+                    run.refreshProcesses()
                 }
             }
 
@@ -201,21 +395,23 @@ func (run LogcatRunner) Run() {
     go func() {
         defer wgOutputWriter.Done()
 
-        var lastLine *adb.AdbLineEntry
-        var currentEntry *models.LogcatEntry
+        merger := newLineMerger(func(entry *models.LogcatEntry) {
+            entry.Package = run.ProcessName(entry.PID)
+            run.handler(entry)
+        })
         for line := range chanLogcatLines {
             entry, err := adb.ParseLogcatLine(line)
             if err != nil {
+                run.stats.AddUnparsed()
                 continue // Ignore parse errors
             }
 
-            if !entry.EqualTimePidLevel(lastLine) && currentEntry != nil {
-                run.DispatchEntry(currentEntry)
-                currentEntry = nil
+            if merger.IsNewEntry(entry) {
+                merger.Flush()
             }
 
             // Check if the PID of the entry is not in the wanted PIDs
-            if len(pids) > 0 && !slices.Contains(pids, entry.PID) {
+            if !run.isWantedPid(entry.PID) {
                 continue
             }
 
@@ -228,46 +424,52 @@ func (run LogcatRunner) Run() {
                 continue
             }
 
-            // Print the logcat line
-            //Check if is the same time/pid/level
-            if entry.EqualTimePidLevel(lastLine) && currentEntry != nil {
-                currentEntry.Message += "\n" + entry.Message
-            }else{
-                currentEntry = &models.LogcatEntry{
-                    Date:       entry.Date,
-                    Time:       entry.Time,
-                    Level:      entry.Level,
-                    Tag:        entry.Tag,
-                    PID:        entry.PID,
-                    TID:        entry.TID,
-                    Message:    entry.Message,
-                }
-                
-            }
-
-            lastLine = &entry
-            
+            // Merge the lines with the same time/pid/level
+            merger.Add(entry)
         }
+
+        merger.Flush()
     }()
 
-    // Start a go function that reads the logcat lines and sends them to the channel
+    // Start a go function that reads the logcat lines and sends them to the channel.
+    // With --reconnect, logcat is started again when it ends while running (the device was lost).
     wgLogcatReader := new(sync.WaitGroup)
     wgLogcatReader.Add(1)
     go func() {
         defer wgLogcatReader.Done()
+        defer close(chanLogcatLines)
 
-        // Read the logcat lines
-        scanner := bufio.NewScanner(stdout)
-        for scanner.Scan() {
-            chanLogcatLines <- scanner.Text()
-        }
+        last := newLastLines()
+        for {
+            // Read the logcat lines, skipping the ones read before logcat was started again
+            scanner := bufio.NewScanner(stdout)
+            scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+            for scanner.Scan() {
+                line := scanner.Text()
+                if last.Skip(line) {
+                    continue
+                }
+                last.Add(line)
+                chanLogcatLines <- line
+            }
 
-        if err := scanner.Err(); err != nil {
-            log.Errorf("%s", err)
-            os.Exit(1)
-        }
+            if err := scanner.Err(); err != nil && run.running.Load() {
+                log.Errorf("%s", err)
+            }
+
+            // Wait for the process to finish
+            cmd.Wait()
+
+            if !run.running.Load() || !run.options.Reconnect {
+                return
+            }
 
-        close(chanLogcatLines)
+            var ok bool
+            if cmd, stdout, ok = run.reconnect(last.Time()); !ok {
+                return
+            }
+            last.Restart()
+        }
     }()
 
     // Wait for the user to press CTRL+C
@@ -275,24 +477,294 @@ func (run LogcatRunner) Run() {
     signal.Notify(c, os.Interrupt, syscall.SIGTERM)
     go func() {
         <-c
-        run.cancel()
-        run.running = false
+        run.Stop()
     }()
 
-    // Wait for the logcat process to finish
-    cmd.Wait()
-
+    // Wait for the logcat output to end and for the process to finish
     wgLogcatReader.Wait()
+
     wgOutputWriter.Wait()
+
+    if run.alerts != nil {
+        run.alerts.Close()
+    }
+    if run.collapser != nil {
+        run.collapser.Close()
+    }
+    if run.limiter != nil {
+        run.limiter.Close()
+    }
+
+    run.stats.Finish()
+    if run.limiter != nil {
+        run.stats.Dropped = run.limiter.Dropped()
+    }
+    if err := run.dispatcher.Close(); err != nil {
+        log.Errorf("%s", err)
+    }
+    if run.logFile != nil {
+        run.stats.BytesWritten = run.logFile.BytesWritten()
+    }
+
+    if run.metricsServer != nil {
+        run.metricsServer.Close()
+    }
+
+    if run.options.Stats {
+        fmt.Fprintf(os.Stderr, "\n%s", run.stats.Summary(run.options.StatsTop, false))
+    }
+    if run.values != nil {
+        fmt.Fprintf(os.Stderr, "\n%s", run.values.Summary())
+    }
 }
 
-func (run LogcatRunner) CheckIgnore(logEntry adb.AdbLineEntry) bool {
+// Starts 'adb logcat', since is the time of the first entry like '01-02 15:04:05.000'
+// or the number of the last lines (all the buffer when empty)
+func (run *LogcatRunner) startLogcat(since string) (*exec.Cmd, io.ReadCloser, error) {
+    args := append([]string{}, run.ADBClient.BaseCmdLogcat[1:]...)
+    if since != "" {
+        args = append(args, "-T", since)
+    }
+
+    cmd := exec.CommandContext(run.ctx, run.ADBClient.BaseCmdLogcat[0], args...)
+
+    // Capture the output of the logcat command
+    stdout, err := cmd.StdoutPipe()
+    if err != nil {
+        return nil, nil, err
+    }
+
+    if err := cmd.Start(); err != nil {
+        return nil, nil, err
+    }
+
+    return cmd, stdout, nil
+}
+
+// Waits for the device and starts logcat again from the time of the last line read
+// (like '01-02 15:04:05.000'), retrying until it works or the runner is stopped
+func (run *LogcatRunner) reconnect(last string) (*exec.Cmd, io.ReadCloser, bool) {
+    since := last
+    if since == "" {
+        since = time.Now().Format("01-02 15:04:05.000")
+    }
 
-    txt := fmt.Sprintf("%s %s %s", logEntry.PID, logEntry.Tag, logEntry.Message)
+    log.Warn("logcat ended, waiting for the device...")
+
+    for run.running.Load() {
+        // Do not restart too fast when logcat ends at once
+        select {
+        case <-run.ctx.Done():
+            return nil, nil, false
+        case <-time.After(time.Second):
+        }
+
+        if err := run.ADBClient.WaitForDevice(run.ctx); err != nil {
+            log.Debug("error waiting for the device", "err", err)
+            continue
+        }
+
+        cmd, stdout, err := run.startLogcat(since)
+        if err != nil {
+            log.Debug("error starting logcat", "err", err)
+            continue
+        }
+
+        log.Info("device reconnected, reading logcat since " + since)
+        if run.metrics != nil {
+            run.metrics.Reconnected()
+        }
+        return cmd, stdout, true
+    }
+
+    return nil, nil, false
+}
+
+// The lines of the last log time read. 'logcat -T <time>' starts again with the
+// entries of that time, so they are skipped after a reconnect.
+type lastLines struct {
+    time     string
+    lines    map[string]int
+    skipping bool
+}
+
+func newLastLines() *lastLines {
+    return &lastLines{
+        lines: map[string]int{},
+    }
+}
+
+// Adds a line read
+func (l *lastLines) Add(line string) {
+    t := reLineTime.FindString(line)
+    if t == "" {
+        return
+    }
+    if t != l.time {
+        l.time = t
+        l.lines = map[string]int{}
+    }
+    l.lines[line]++
+}
+
+// Gets the log time of the last line read, empty before the first one
+func (l *lastLines) Time() string {
+    return l.time
+}
+
+// Starts skipping the lines read before, logcat was started again
+func (l *lastLines) Restart() {
+    l.skipping = true
+}
+
+// Checks if the line was read before the restart. The skipping ends at the first
+// line of logcat that was not read before.
+func (l *lastLines) Skip(line string) bool {
+    if !l.skipping {
+        return false
+    }
+
+    t := reLineTime.FindString(line)
+    if t == "" {
+        // Like '--------- beginning of main'
+        return false
+    }
+    if t == l.time && l.lines[line] > 0 {
+        l.lines[line]--
+        return true
+    }
+
+    l.skipping = false
+    return false
+}
+
+// Gets the process name (the package for apps) of a PID, as seen in the last 'adb shell ps'
+func (run *LogcatRunner) ProcessName(pid string) string {
+    run.mutex.Lock()
+    defer run.mutex.Unlock()
+
+    return run.processNames[pid]
+}
+
+// Replaces the output of the entries, by default they go to the sinks (the terminal and the log file)
+func (run *LogcatRunner) SetOutput(output EntryHandler) {
+    run.output = output
+}
+
+func (run *LogcatRunner) refreshProcesses() {
+    processes, err := run.ADBClient.GetProcesses()
+    if err != nil {
+        log.Debug("error getting the processes", "err", err)
+        return
+    }
+
+    names := map[string]string{}
+    for _, p := range processes {
+        names[p.PID] = p.NAME
+    }
+
+    run.mutex.Lock()
+    old := run.processNames
+    run.processNames = names
+    run.mutex.Unlock()
+
+    if run.metrics != nil && len(old) > 0 {
+        run.countRestarts(old, names)
+    }
+}
+
+// Counts the apps that restarted: a package (a name with a dot, so not the shell
+// commands) running with a new PID while its old PIDs are gone
+func (run *LogcatRunner) countRestarts(old map[string]string, names map[string]string) {
+    oldPids := map[string][]string{}
+    for pid, name := range old {
+        if strings.Contains(name, ".") {
+            oldPids[name] = append(oldPids[name], pid)
+        }
+    }
+
+    restarted := map[string]bool{}
+    for pid, name := range names {
+        pids, ok := oldPids[name]
+        if !ok || slices.Contains(pids, pid) {
+            continue
+        }
+
+        gone := true
+        for _, p := range pids {
+            if names[p] == name {
+                gone = false
+            }
+        }
+        if gone {
+            restarted[name] = true
+        }
+    }
+
+    for name := range restarted {
+        log.Debug("process restarted", "name", name)
+        run.metrics.ProcessRestarted(name)
+    }
+}
+
+// Gets the PIDs of the processes of a package
+func (run *LogcatRunner) packagePids(slug string) []string {
+    run.mutex.Lock()
+    defer run.mutex.Unlock()
+
+    pids := []string{}
+    for pid, name := range run.processNames {
+        if strings.EqualFold(name, slug) {
+            pids = append(pids, pid)
+        }
+    }
+
+    return pids
+}
+
+func (run *LogcatRunner) setPids(pids []string) {
+    run.mutex.Lock()
+    defer run.mutex.Unlock()
+
+    run.pids = pids
+}
+
+// Checks if the PID is one of the wanted packages (all are wanted without packages)
+func (run *LogcatRunner) isWantedPid(pid string) bool {
+    run.mutex.Lock()
+    defer run.mutex.Unlock()
+
+    return len(run.pids) == 0 || slices.Contains(run.pids, pid)
+}
+
+// Gets the stats of the session
+func (run *LogcatRunner) Stats() *Stats {
+    return run.stats
+}
+
+// Stops the logcat process, Run returns after everything is written
+func (run *LogcatRunner) Stop() {
+    run.running.Store(false)
+    run.cancel()
+}
+
+// Checks if the runner was not stopped
+func (run *LogcatRunner) IsRunning() bool {
+    return run.running.Load()
+}
+
+func (run *LogcatRunner) CheckIgnore(logEntry adb.AdbLineEntry) bool {
+    return ignoreEntry(run.options, logEntry.PID, logEntry.Tag, logEntry.Message)
+}
+
+// Checks the --include and --exclude terms
+func ignoreEntry(opts Options, pid string, tag string, message string) bool {
+
+    txt := fmt.Sprintf("%s %s %s", pid, tag, message)
 
     // Check if the tag is to be included
-    if len(run.options.IncludeFilterList) > 0 {
-        for _, slug := range run.options.IncludeFilterList {
+    if len(opts.IncludeFilterList) > 0 {
+        for _, slug := range opts.IncludeFilterList {
             if strings.Contains(txt, slug) {
                 return false
             }
@@ -301,8 +773,8 @@ func (run LogcatRunner) CheckIgnore(logEntry adb.AdbLineEntry) bool {
     }
 
     // Check if the tag is to be ignored
-    if len(run.options.ExcludeFilterList) > 0 {
-        for _, slug := range run.options.ExcludeFilterList {
+    if len(opts.ExcludeFilterList) > 0 {
+        for _, slug := range opts.ExcludeFilterList {
             if strings.Contains(txt, slug) {
                 return true
             }
@@ -313,16 +785,8 @@ func (run LogcatRunner) CheckIgnore(logEntry adb.AdbLineEntry) bool {
     return false
 }
 
-func (run LogcatRunner) DispatchEntry(logEntry *models.LogcatEntry) {
-    if !run.running {
-        return
-    }
-
-    fmt.Fprintln(color.Output, logEntry.FormatAnsiString(run.options.ShowTime, run.options.ShowPid, true))
-
-    if run.options.UseAnsiLog {
-        logEntry.ToAnsiFile(run.logFile)
-    }else {
-        logEntry.ToFile(run.logFile)
-    }
+// Sends an entry to the sinks, the entries flushed at the end (the collapsed ones) are
+// written until the dispatcher is closed
+func (run *LogcatRunner) DispatchEntry(logEntry *models.LogcatEntry) {
+    run.dispatcher.Handle(logEntry)
 }
\ No newline at end of file
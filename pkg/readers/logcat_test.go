@@ -0,0 +1,88 @@
+package readers
+
+import (
+    "slices"
+    "testing"
+)
+
+func TestLastLinesSkip(t *testing.T) {
+    before := []string{
+        "01-02 10:00:00.100  100  101 I A       : one",
+        "01-02 10:00:00.200  100  101 I A       : two",
+        "01-02 10:00:00.200  100  101 I A       : two",
+        "01-02 10:00:00.200  100  102 I B       : three",
+    }
+
+    tests := []struct {
+        name  string
+        after []string
+        want  []string
+    }{
+        {
+            name: "same time again",
+            after: []string{
+                "--------- beginning of main",
+                "01-02 10:00:00.200  100  101 I A       : two",
+                "01-02 10:00:00.200  100  101 I A       : two",
+                "01-02 10:00:00.200  100  102 I B       : three",
+                "01-02 10:00:00.300  100  101 I A       : four",
+            },
+            want: []string{
+                "--------- beginning of main",
+                "01-02 10:00:00.300  100  101 I A       : four",
+            },
+        },
+        {
+            name: "new line at the same time",
+            after: []string{
+                "01-02 10:00:00.200  100  101 I A       : two",
+                "01-02 10:00:00.200  100  103 I C       : new",
+                "01-02 10:00:00.200  100  101 I A       : two",
+            },
+            want: []string{
+                "01-02 10:00:00.200  100  103 I C       : new",
+                "01-02 10:00:00.200  100  101 I A       : two",
+            },
+        },
+        {
+            name: "repeated more times than read",
+            after: []string{
+                "01-02 10:00:00.200  100  101 I A       : two",
+                "01-02 10:00:00.200  100  101 I A       : two",
+                "01-02 10:00:00.200  100  101 I A       : two",
+            },
+            want: []string{
+                "01-02 10:00:00.200  100  101 I A       : two",
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            last := newLastLines()
+            for _, line := range before {
+                if last.Skip(line) {
+                    t.Fatalf("skipped %q before the restart", line)
+                }
+                last.Add(line)
+            }
+            if last.Time() != "01-02 10:00:00.200" {
+                t.Fatalf("got time %q", last.Time())
+            }
+
+            last.Restart()
+            got := []string{}
+            for _, line := range tt.after {
+                if last.Skip(line) {
+                    continue
+                }
+                last.Add(line)
+                got = append(got, line)
+            }
+
+            if !slices.Equal(got, tt.want) {
+                t.Errorf("got %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
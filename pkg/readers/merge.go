@@ -0,0 +1,53 @@
+package readers
+
+import (
+    "github.com/helviojunior/adbcat/pkg/adb"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// lineMerger joins the consecutive lines with the same time/pid/level
+// (like the lines of a stack trace) into one entry
+type lineMerger struct {
+    next    EntryHandler
+    last    *adb.AdbLineEntry
+    current *models.LogcatEntry
+}
+
+func newLineMerger(next EntryHandler) *lineMerger {
+    return &lineMerger{
+        next: next,
+    }
+}
+
+// Adds a line to the current entry or starts a new one
+func (m *lineMerger) Add(line adb.AdbLineEntry) {
+    if line.EqualTimePidLevel(m.last) && m.current != nil {
+        m.current.Message += "\n" + line.Message
+    }else{
+        m.Flush()
+        m.current = &models.LogcatEntry{
+            Date:       line.Date,
+            Time:       line.Time,
+            Level:      line.Level,
+            Tag:        line.Tag,
+            PID:        line.PID,
+            TID:        line.TID,
+            Message:    line.Message,
+        }
+    }
+
+    m.last = &line
+}
+
+// Checks if the line can not be merged to the current entry
+func (m *lineMerger) IsNewEntry(line adb.AdbLineEntry) bool {
+    return !line.EqualTimePidLevel(m.last)
+}
+
+// Sends the current entry to the next stage
+func (m *lineMerger) Flush() {
+    if m.current != nil {
+        m.next(m.current)
+        m.current = nil
+    }
+}
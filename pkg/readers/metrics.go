@@ -0,0 +1,119 @@
+package readers
+
+import (
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/metrics"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// A device that could not be read is read again after this time
+const deviceRetryInterval = 10 * time.Second
+
+// Metrics counts the entries and the events of the session for the Prometheus
+// endpoint (--metrics-addr). It is a stage of the chain, after Stats, so it counts
+// the entries dropped by the later stages too.
+type Metrics struct {
+    next     EntryHandler
+    registry *metrics.Registry
+
+    // The serial of the device, the device label of all the metrics
+    deviceFn    func() *models.DeviceInfo
+    deviceMutex sync.Mutex
+    deviceLast  time.Time
+    device      string
+
+    // The tags and the process names are capped, the others are counted as "other"
+    tags      *metrics.ValueCap
+    processes *metrics.ValueCap
+
+    entries    *metrics.CounterVec
+    byTag      *metrics.CounterVec
+    byPackage  *metrics.CounterVec
+    restarts   *metrics.CounterVec
+    reconnects *metrics.CounterVec
+}
+
+// Creates the metrics, the device (may return nil) is read on the first use.
+// maxTags is the maximum number of tags (and of process names) with their own series.
+func NewMetrics(maxTags int, device func() *models.DeviceInfo, next EntryHandler) *Metrics {
+    m := &Metrics{
+        next:      next,
+        registry:  metrics.NewRegistry(),
+        deviceFn:  device,
+        tags:      metrics.NewValueCap(maxTags),
+        processes: metrics.NewValueCap(maxTags),
+    }
+
+    m.entries = m.registry.Counter("adbcat_entries_total",
+        "Log entries read, by level.", "device", "level")
+    m.byTag = m.registry.Counter("adbcat_entries_by_tag_total",
+        "Log entries read, by tag. The tags after the cap are counted as \"other\".", "device", "tag")
+    m.byPackage = m.registry.Counter("adbcat_entries_by_package_total",
+        "Log entries read, by package (process name).", "device", "package")
+    m.restarts = m.registry.Counter("adbcat_process_restarts_total",
+        "Apps running again with a new PID.", "device", "package")
+    m.reconnects = m.registry.Counter("adbcat_adb_reconnects_total",
+        "Times logcat was started again after the device was lost.", "device")
+
+    return m
+}
+
+// Handles one entry, it is an EntryHandler
+func (m *Metrics) Handle(entry *models.LogcatEntry) {
+    n := 1.0
+    if entry.Count > 1 {
+        n = float64(entry.Count)
+    }
+
+    pkg := entry.Package
+    if pkg == "" {
+        pkg = "unknown"
+    }
+
+    device := m.Device()
+    m.entries.Add(n, device, entry.Level)
+    m.byTag.Add(n, device, m.tags.Value(strings.TrimSpace(entry.Tag)))
+    m.byPackage.Add(n, device, m.processes.Value(pkg))
+
+    if m.next != nil {
+        m.next(entry)
+    }
+}
+
+// Counts an app that is running again with a new PID
+func (m *Metrics) ProcessRestarted(name string) {
+    m.restarts.Inc(m.Device(), m.processes.Value(name))
+}
+
+// Counts a new start of logcat after the device was lost
+func (m *Metrics) Reconnected() {
+    m.reconnects.Inc(m.Device())
+}
+
+// Gets the device label: the serial of the device, "unknown" while it could not be
+// read. It is read again every deviceRetryInterval until it works.
+func (m *Metrics) Device() string {
+    m.deviceMutex.Lock()
+    defer m.deviceMutex.Unlock()
+
+    if m.device != "" {
+        return m.device
+    }
+    if m.deviceFn == nil || time.Since(m.deviceLast) < deviceRetryInterval {
+        return "unknown"
+    }
+    m.deviceLast = time.Now()
+    if d := m.deviceFn(); d != nil && d.Serial != "" {
+        m.device = d.Serial
+        return m.device
+    }
+    return "unknown"
+}
+
+// Gets the registry, to add the metrics read at scrape time
+func (m *Metrics) Registry() *metrics.Registry {
+    return m.registry
+}
@@ -1,6 +1,7 @@
 package readers
 
 import (
+    "time"
     //"github.com/helviojunior/adbcat/pkg/models"
 )
 
@@ -13,6 +14,18 @@ type Options struct {
 
     IncludeFilterList []string
 
+    // Expressions like "field.status>=500 && tag==OkHttp" the entries must match
+    Filters []string
+
+    // Extract the key=value pairs and the JSON fields of the messages
+    Fields bool
+
+    // Rules in the format "style:regex" to highlight inside the messages
+    HighlightRules []string
+
+    // Colors in the format "Tag=style" overriding the tag palette
+    TagColors []string
+
     LogFile string
 
     MinLevel string
@@ -31,6 +44,110 @@ type Options struct {
     ShowPid bool
 
     UseAnsiLog bool
+
+    // Wrap the long messages at the console width instead of truncating them
+    Wrap bool
+
+    // Pretty print the JSON and the XML found in the messages
+    PrettyJSON bool
+    PrettyXML bool
+
+    // Go text/template of the output lines, replacing the fixed columns
+    Template string
+
+    // The format of the log file: text, ansi, json or logcat
+    LogFileFormat string
+
+    // Elasticsearch/OpenSearch exporter, enabled by the URL
+    EsURL string
+    EsIndex string
+    EsAPIKey string
+    EsBatchSize int
+    EsBatchInterval time.Duration
+
+    // Grafana Loki exporter, enabled by the URL
+    LokiURL string
+    // Entry values used as labels: device, package, level and tag
+    LokiLabels []string
+    // Fixed labels like "env=lab"
+    LokiStaticLabels []string
+    LokiTenant string
+    LokiBatchSize int
+    LokiBatchInterval time.Duration
+
+    // OpenTelemetry exporter (OTLP/HTTP), enabled by the endpoint
+    OtlpEndpoint string
+    // Headers of the requests like "Authorization=Bearer xyz"
+    OtlpHeaders []string
+    OtlpServiceName string
+    OtlpBatchSize int
+    OtlpBatchInterval time.Duration
+
+    // Syslog forwarder, enabled by the URL (udp://, tcp:// or tls://)
+    SyslogURL string
+    SyslogFacility string
+    SyslogInsecure bool
+    SyslogBuffer int
+
+    // Filter expressions that post an alert to the webhook
+    Alerts []string
+    AlertWebhook string
+    // The body of the alerts: json, slack or a Go template
+    AlertTemplate string
+    // Minimum time between two alerts of a rule
+    AlertDebounce time.Duration
+    // Entries sent with the alert, before and after the triggering one
+    AlertBefore int
+    AlertAfter int
+
+    // Commands (scripts) that read the entries from their input, one JSON object per line
+    Exec []string
+
+    // What the sinks do when their queue is full, like "loki=block" or "drop-newest" (all)
+    QueuePolicies []string
+
+    // Address of the Prometheus /metrics endpoint like ":9102", disabled when empty
+    MetricsAddr string
+    // Maximum tags (and packages) with their own series, the others are "other"
+    MetricsMaxTags int
+
+    // Rules like "name=regex" extracting numbers from the messages, as histograms
+    ValueMetrics []string
+
+    // Entries of the history of the server shown first by attach
+    AttachTail int
+
+    // Where logcat starts (adb logcat -T): a time like '01-02 15:04:05.000' or a number
+    // of the last lines, all the buffer when empty
+    Since string
+
+    // Start logcat again when it ends while running (the device was lost)
+    Reconnect bool
+
+    // Fold repeated messages (same tag and message) into one line
+    Collapse bool
+    // Fold the repeated messages seen within this window, not only the consecutive ones
+    CollapseWindow time.Duration
+    // Ignore the numbers when comparing the messages
+    CollapseDigits bool
+
+    // Rate limits by tag like "Tag=10/s" (the tag * sets the limit of each tag)
+    RateLimits []string
+    // Rate limit of all the tags together like "200/s"
+    MaxRate string
+    // Fraction of the entries to keep by tag like "Tag=0.1"
+    Samples []string
+
+    // Print a summary of the session at the end
+    Stats bool
+    // How many tags and PIDs the summary shows
+    StatsTop int
+
+    // When to use colors: never, auto or always
+    ColorMode string
+
+    // The name of a built-in theme or the path of a theme file
+    Theme string
 }
 
 // Logging is log related options
@@ -54,6 +171,10 @@ func NewDefaultOptions() *Options {
         },
         ExcludeFilterList: []string{},
         IncludeFilterList: []string{},
+        Filters: []string{},
+        Fields: false,
+        HighlightRules: []string{},
+        TagColors: []string{},
         LogFile: "",
         MinLevel: "V",
         UseDevice: false,
@@ -63,5 +184,54 @@ func NewDefaultOptions() *Options {
         AdbBinPath: "",
         ClearOutput: false,
         UseAnsiLog: false,
+        Wrap: false,
+        Template: "",
+        PrettyJSON: false,
+        PrettyXML: false,
+        LogFileFormat: "text",
+        EsURL: "",
+        EsIndex: "adbcat-%Y.%m.%d",
+        EsAPIKey: "",
+        EsBatchSize: 500,
+        EsBatchInterval: 5 * time.Second,
+        LokiURL: "",
+        LokiLabels: []string{"device", "package", "level"},
+        LokiStaticLabels: []string{},
+        LokiTenant: "",
+        LokiBatchSize: 1000,
+        LokiBatchInterval: 2 * time.Second,
+        OtlpEndpoint: "",
+        OtlpHeaders: []string{},
+        OtlpServiceName: "adbcat",
+        OtlpBatchSize: 512,
+        OtlpBatchInterval: 2 * time.Second,
+        SyslogURL: "",
+        SyslogFacility: "local0",
+        SyslogInsecure: false,
+        SyslogBuffer: 10000,
+        Alerts: []string{},
+        AlertWebhook: "",
+        AlertTemplate: "json",
+        AlertDebounce: 5 * time.Minute,
+        AlertBefore: 20,
+        AlertAfter: 10,
+        Exec: []string{},
+        QueuePolicies: []string{},
+        MetricsAddr: "",
+        MetricsMaxTags: 200,
+        ValueMetrics: []string{},
+        AttachTail: 0,
+        Since: "",
+        Reconnect: false,
+        Collapse: false,
+        CollapseWindow: 0,
+        CollapseDigits: false,
+        RateLimits: []string{},
+        MaxRate: "",
+        Samples: []string{},
+        Stats: false,
+        StatsTop: 10,
+        ColorMode: "auto",
+        Theme: "dark",
     }
 }
\ No newline at end of file
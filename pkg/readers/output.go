@@ -0,0 +1,139 @@
+package readers
+
+import (
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+    "github.com/helviojunior/adbcat/pkg/sinks"
+)
+
+// Creates the dispatcher of the entries with the sinks of the options: the terminal,
+// the log file and the exporters. The device and the packages go to the header of the .logcat files.
+// Returns the file sink as well (nil without a log file) for its size.
+func openOutputs(opts Options, device func() *models.DeviceInfo, packages []string) (*sinks.Dispatcher, *sinks.FileSink, error) {
+    format := opts.LogFileFormat
+    if opts.UseAnsiLog {
+        format = "ansi"
+    }
+    if err := sinks.CheckFileFormat(format); err != nil {
+        return nil, nil, err
+    }
+    policies, err := sinks.ParsePolicyRules(opts.QueuePolicies)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    dispatcher := sinks.NewDispatcher()
+
+    // Adds a sink with the --queue-policy of its kind, or of all the sinks
+    add := func(sink sinks.Sink, options sinks.QueueOptions) {
+        if p, ok := policies[sinks.SinkKind(sink.Name())]; ok {
+            options.Policy = p
+        } else if p, ok := policies[""]; ok {
+            options.Policy = p
+        }
+        dispatcher.Add(sink, options)
+    }
+
+    add(sinks.NewTerminalSink(opts.ShowTime, opts.ShowPid), sinks.DefaultQueueOptions())
+
+    var file *sinks.FileSink
+    if opts.LogFile != "" {
+        file, err = sinks.NewFileSink(opts.LogFile, format, device, packages)
+        if err != nil {
+            dispatcher.Close()
+            return nil, nil, err
+        }
+        add(file, sinks.DefaultQueueOptions())
+    }
+
+    if opts.EsURL != "" {
+        es, err := sinks.NewElasticSink(sinks.ElasticOptions{
+            URL:       opts.EsURL,
+            Index:     opts.EsIndex,
+            APIKey:    opts.EsAPIKey,
+            BatchSize: opts.EsBatchSize,
+        }, device)
+        if err != nil {
+            dispatcher.Close()
+            return nil, nil, err
+        }
+        add(es, sinks.ExporterQueueOptions(opts.EsBatchInterval))
+    }
+
+    if opts.LokiURL != "" {
+        static := map[string]string{}
+        for _, l := range opts.LokiStaticLabels {
+            k, v, ok := strings.Cut(l, "=")
+            if !ok || strings.TrimSpace(k) == "" {
+                dispatcher.Close()
+                return nil, nil, fmt.Errorf("invalid Loki label '%s', use name=value", l)
+            }
+            static[strings.TrimSpace(k)] = strings.TrimSpace(v)
+        }
+
+        loki, err := sinks.NewLokiSink(sinks.LokiOptions{
+            URL:          opts.LokiURL,
+            Labels:       opts.LokiLabels,
+            StaticLabels: static,
+            Tenant:       opts.LokiTenant,
+            BatchSize:    opts.LokiBatchSize,
+        }, device)
+        if err != nil {
+            dispatcher.Close()
+            return nil, nil, err
+        }
+        add(loki, sinks.ExporterQueueOptions(opts.LokiBatchInterval))
+    }
+
+    if opts.OtlpEndpoint != "" {
+        headers := map[string]string{}
+        for _, h := range opts.OtlpHeaders {
+            k, v, ok := strings.Cut(h, "=")
+            if !ok || strings.TrimSpace(k) == "" {
+                dispatcher.Close()
+                return nil, nil, fmt.Errorf("invalid OTLP header '%s', use name=value", h)
+            }
+            headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+        }
+
+        otlp, err := sinks.NewOtlpSink(sinks.OtlpOptions{
+            Endpoint:    opts.OtlpEndpoint,
+            Headers:     headers,
+            ServiceName: opts.OtlpServiceName,
+            BatchSize:   opts.OtlpBatchSize,
+        }, device)
+        if err != nil {
+            dispatcher.Close()
+            return nil, nil, err
+        }
+        add(otlp, sinks.ExporterQueueOptions(opts.OtlpBatchInterval))
+    }
+
+    if opts.SyslogURL != "" {
+        syslog, err := sinks.NewSyslogSink(sinks.SyslogOptions{
+            URL:        opts.SyslogURL,
+            Facility:   opts.SyslogFacility,
+            Insecure:   opts.SyslogInsecure,
+            BufferSize: opts.SyslogBuffer,
+        }, device)
+        if err != nil {
+            dispatcher.Close()
+            return nil, nil, err
+        }
+        add(syslog, sinks.ExporterQueueOptions(time.Second))
+    }
+
+    for _, command := range opts.Exec {
+        script, err := sinks.NewExecSink(command)
+        if err != nil {
+            dispatcher.Close()
+            return nil, nil, err
+        }
+        add(script, sinks.ExporterQueueOptions(time.Second))
+    }
+
+    return dispatcher, file, nil
+}
@@ -0,0 +1,313 @@
+package readers
+
+import (
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/helviojunior/adbcat/internal/tools"
+    "github.com/helviojunior/adbcat/pkg/log"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+const (
+    // How often the dropped entries are reported
+    rateLimitReportInterval = 10 * time.Second
+
+    // The tag that sets the rate limit of every tag without its own limit
+    AnyTag = "*"
+
+    // Why an entry was dropped, for the reports and the metrics
+    DropRateLimit = "rate_limit"
+    DropSample    = "sample"
+)
+
+// RateLimiter drops the entries of noisy tags before they are written.
+//
+// Each tag with a limit (--rate-limit Tag=10/s) has its own token bucket,
+// the --max-rate bucket is shared by all tags (errors and fatal entries are
+// never dropped by it) and --sample Tag=0.1 keeps a fraction of the entries.
+type RateLimiter struct {
+    next EntryHandler
+
+    tagRates map[string]float64
+    samples  map[string]float64
+    global   *tokenBucket
+
+    mutex   sync.Mutex
+    buckets map[string]*tokenBucket
+    acc     map[string]float64
+
+    // Dropped entries by tag and reason, since the last report, and in total
+    dropped      map[dropKey]int
+    totalDropped int
+    totalSampled int
+
+    stop chan bool
+    wg   sync.WaitGroup
+}
+
+type dropKey struct {
+    tag    string
+    reason string
+}
+
+type tokenBucket struct {
+    rate   float64 // Tokens per second
+    burst  float64
+    tokens float64
+    last   time.Time
+}
+
+// Creates a rate limiter that writes the entries to next.
+// rateLimits are like "Tag=10/s", maxRate like "200/s" and samples like "Tag=0.1".
+func NewRateLimiter(rateLimits []string, maxRate string, samples []string, next EntryHandler) (*RateLimiter, error) {
+    rl := &RateLimiter{
+        next:     next,
+        tagRates: map[string]float64{},
+        samples:  map[string]float64{},
+        buckets:  map[string]*tokenBucket{},
+        acc:      map[string]float64{},
+        dropped:  map[dropKey]int{},
+        stop:     make(chan bool),
+    }
+
+    for _, s := range rateLimits {
+        tag, value, err := splitTagValue(s)
+        if err != nil {
+            return nil, err
+        }
+        rate, err := ParseRate(value)
+        if err != nil {
+            return nil, err
+        }
+        rl.tagRates[tag] = rate
+    }
+
+    if maxRate != "" {
+        rate, err := ParseRate(maxRate)
+        if err != nil {
+            return nil, err
+        }
+        rl.global = newTokenBucket(rate)
+    }
+
+    for _, s := range samples {
+        tag, value, err := splitTagValue(s)
+        if err != nil {
+            return nil, err
+        }
+        f, err := strconv.ParseFloat(value, 64)
+        if err != nil || f < 0 || f > 1 {
+            return nil, fmt.Errorf("invalid sample '%s', use a fraction between 0 and 1", s)
+        }
+        rl.samples[tag] = f
+    }
+
+    rl.wg.Add(1)
+    go rl.reportLoop()
+
+    return rl, nil
+}
+
+// Parses a rate like "10/s", "600/m", "1000/h" or "10" (per second) into entries per second
+func ParseRate(rate string) (float64, error) {
+    value := strings.TrimSpace(rate)
+    unit := time.Second
+    if p := strings.SplitN(value, "/", 2); len(p) == 2 {
+        value = p[0]
+        switch strings.ToLower(strings.TrimSpace(p[1])) {
+        case "s", "sec", "second":
+            unit = time.Second
+        case "m", "min", "minute":
+            unit = time.Minute
+        case "h", "hour":
+            unit = time.Hour
+        default:
+            return 0, fmt.Errorf("invalid rate '%s', use N/s, N/m or N/h", rate)
+        }
+    }
+
+    n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+    if err != nil || n <= 0 {
+        return 0, fmt.Errorf("invalid rate '%s', use N/s, N/m or N/h", rate)
+    }
+
+    return n / unit.Seconds(), nil
+}
+
+// Handles one entry, it is an EntryHandler
+func (rl *RateLimiter) Handle(entry *models.LogcatEntry) {
+    if rl.allow(entry) {
+        rl.next(entry)
+    }
+}
+
+// Gets the number of entries dropped by the rate limits and by the sampling
+func (rl *RateLimiter) Dropped() int {
+    rl.mutex.Lock()
+    defer rl.mutex.Unlock()
+
+    return rl.totalDropped + rl.totalSampled
+}
+
+// Gets the number of entries dropped by the reason: DropRateLimit or DropSample
+func (rl *RateLimiter) DroppedBy(reason string) int {
+    rl.mutex.Lock()
+    defer rl.mutex.Unlock()
+
+    if reason == DropSample {
+        return rl.totalSampled
+    }
+    return rl.totalDropped
+}
+
+// Reports what is left and stops the report loop
+func (rl *RateLimiter) Close() {
+    close(rl.stop)
+    rl.wg.Wait()
+
+    rl.report()
+}
+
+func (rl *RateLimiter) allow(entry *models.LogcatEntry) bool {
+    rl.mutex.Lock()
+    defer rl.mutex.Unlock()
+
+    now := time.Now()
+    tag := strings.TrimSpace(entry.Tag)
+
+    if f, ok := rl.lookup(rl.samples, tag); ok {
+        // Start full, so the first entry of the tag is kept
+        if _, ok := rl.acc[tag]; !ok && f > 0 {
+            rl.acc[tag] = 1 - f
+        }
+        rl.acc[tag] += f
+        if rl.acc[tag] < 1 {
+            rl.dropped[dropKey{tag, DropSample}]++
+            rl.totalSampled++
+            return false
+        }
+        rl.acc[tag] -= 1
+    }
+
+    if rate, ok := rl.lookup(rl.tagRates, tag); ok {
+        b, ok := rl.buckets[tag]
+        if !ok {
+            b = newTokenBucket(rate)
+            rl.buckets[tag] = b
+        }
+        if !b.take(now) {
+            rl.dropped[dropKey{tag, DropRateLimit}]++
+            rl.totalDropped++
+            return false
+        }
+    }
+
+    if rl.global != nil && models.LevelMap[entry.Level] < models.LevelError {
+        if !rl.global.take(now) {
+            rl.dropped[dropKey{tag, DropRateLimit}]++
+            rl.totalDropped++
+            return false
+        }
+    }
+
+    return true
+}
+
+// Gets the value of the tag or the value set for any tag
+func (rl *RateLimiter) lookup(values map[string]float64, tag string) (float64, bool) {
+    if v, ok := values[tag]; ok {
+        return v, true
+    }
+    v, ok := values[AnyTag]
+    return v, ok
+}
+
+func (rl *RateLimiter) reportLoop() {
+    defer rl.wg.Done()
+
+    ticker := time.NewTicker(rateLimitReportInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-rl.stop:
+            return
+        case <-ticker.C:
+            rl.report()
+        }
+    }
+}
+
+// Logs the entries dropped by tag and reason since the last report
+func (rl *RateLimiter) report() {
+    rl.mutex.Lock()
+    dropped := rl.dropped
+    rl.dropped = map[dropKey]int{}
+    rl.mutex.Unlock()
+
+    keys := []dropKey{}
+    for k := range dropped {
+        keys = append(keys, k)
+    }
+    sort.Slice(keys, func(i, j int) bool {
+        if dropped[keys[i]] != dropped[keys[j]] {
+            return dropped[keys[i]] > dropped[keys[j]]
+        }
+        return keys[i].tag < keys[j].tag
+    })
+
+    for _, k := range keys {
+        if k.reason == DropSample {
+            log.Warnf("Sampling dropped %s entries from tag '%s'", tools.FormatInt(dropped[k]), k.tag)
+        }else{
+            log.Warnf("Rate limit dropped %s entries from tag '%s'", tools.FormatInt(dropped[k]), k.tag)
+        }
+    }
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+    burst := rate
+    if burst < 1 {
+        burst = 1
+    }
+
+    return &tokenBucket{
+        rate:   rate,
+        burst:  burst,
+        tokens: burst,
+        last:   time.Now(),
+    }
+}
+
+// Takes a token if there is one available
+func (b *tokenBucket) take(now time.Time) bool {
+    if now.After(b.last) {
+        b.tokens += now.Sub(b.last).Seconds() * b.rate
+        if b.tokens > b.burst {
+            b.tokens = b.burst
+        }
+        b.last = now
+    }
+
+    if b.tokens < 1 {
+        return false
+    }
+
+    b.tokens -= 1
+    return true
+}
+
+// Splits "Tag=value", the tag may be * for any tag
+func splitTagValue(s string) (string, string, error) {
+    p := strings.SplitN(s, "=", 2)
+    if len(p) != 2 || strings.TrimSpace(p[0]) == "" || strings.TrimSpace(p[1]) == "" {
+        return "", "", fmt.Errorf("invalid value '%s', use the format 'Tag=value'", s)
+    }
+
+    return strings.TrimSpace(p[0]), strings.TrimSpace(p[1]), nil
+}
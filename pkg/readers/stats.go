@@ -0,0 +1,246 @@
+package readers
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/charmbracelet/lipgloss"
+    "github.com/charmbracelet/lipgloss/table"
+    "github.com/helviojunior/adbcat/internal/tools"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// Stats counts the entries of a session. It runs after the field stage, so it counts
+// only the entries that match the --filter expressions, and before the collapse and
+// the rate limits, so it counts the entries they fold or drop too
+type Stats struct {
+    next EntryHandler
+
+    mutex sync.Mutex
+
+    // Wall clock of the session
+    Started  time.Time
+    Finished time.Time
+
+    // Log time of the first and the last entry
+    First time.Time
+    Last  time.Time
+
+    Entries int
+    Levels  map[string]int
+    Tags    map[string]int
+    PIDs    map[string]int
+    Errors  map[string]int // Error entries by tag
+    Fatals  map[string]int // Fatal entries by tag
+
+    BytesWritten int64
+    Dropped      int // Entries dropped by the rate limits and the sampling
+    Unparsed     int // Lines that could not be parsed
+}
+
+// A counter of the summary tables
+type statsItem struct {
+    Name  string
+    Count int
+}
+
+// Creates the stats, next may be nil when the entries only need to be counted
+func NewStats(next EntryHandler) *Stats {
+    return &Stats{
+        next:    next,
+        Started: time.Now(),
+        Levels:  map[string]int{},
+        Tags:    map[string]int{},
+        PIDs:    map[string]int{},
+        Errors:  map[string]int{},
+        Fatals:  map[string]int{},
+    }
+}
+
+// Handles one entry, it is an EntryHandler
+func (s *Stats) Handle(entry *models.LogcatEntry) {
+    s.Add(entry)
+
+    if s.next != nil {
+        s.next(entry)
+    }
+}
+
+// Counts one entry, collapsed entries count as many as they were repeated
+func (s *Stats) Add(entry *models.LogcatEntry) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    n := 1
+    if entry.Count > 1 {
+        n = entry.Count
+    }
+
+    tag := strings.TrimSpace(entry.Tag)
+
+    s.Entries += n
+    s.Levels[entry.Level] += n
+    s.Tags[tag] += n
+    s.PIDs[entry.PID] += n
+
+    switch entry.Level {
+    case "E":
+        s.Errors[tag] += n
+    case "F":
+        s.Fatals[tag] += n
+    }
+
+    if ts, err := entry.Timestamp(); err == nil {
+        if s.First.IsZero() || ts.Before(s.First) {
+            s.First = ts
+        }
+        if ts.After(s.Last) {
+            s.Last = ts
+        }
+    }
+}
+
+// Counts a line that could not be parsed
+func (s *Stats) AddUnparsed() {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    s.Unparsed++
+}
+
+// Gets the number of lines that could not be parsed
+func (s *Stats) UnparsedLines() int {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    return s.Unparsed
+}
+
+// Marks the end of the session
+func (s *Stats) Finish() {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    s.Finished = time.Now()
+}
+
+// Gets the duration of the session, or of the logs when it was read from files
+func (s *Stats) Duration(fromLogs bool) time.Duration {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    if fromLogs {
+        return s.Last.Sub(s.First)
+    }
+
+    end := s.Finished
+    if end.IsZero() {
+        end = time.Now()
+    }
+    return end.Sub(s.Started)
+}
+
+// Renders the summary tables with the top N tags and PIDs
+func (s *Stats) Summary(top int, fromLogs bool) string {
+    duration := s.Duration(fromLogs).Round(time.Millisecond)
+
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    sb := strings.Builder{}
+
+    // General
+    t := newStatsTable("Summary", "")
+    t.Row("Entries", tools.FormatInt(s.Entries))
+    t.Row("Duration", duration.String())
+    if duration > 0 {
+        t.Row("Entries/s", fmt.Sprintf("%.1f", float64(s.Entries)/duration.Seconds()))
+    }
+    if !fromLogs {
+        t.Row("Bytes written", tools.FormatInt64(s.BytesWritten))
+        t.Row("Dropped", tools.FormatInt(s.Dropped))
+    }
+    t.Row("Unparsed lines", tools.FormatInt(s.Unparsed))
+    sb.WriteString(t.Render() + "\n")
+
+    // Levels, in the level order
+    t = newStatsTable("Level", "Entries")
+    for _, lvl := range []string{"V", "D", "I", "W", "E", "F"} {
+        t.Row(lvl, tools.FormatInt(s.Levels[lvl]))
+    }
+    sb.WriteString(t.Render() + "\n")
+
+    t = newStatsTable("Top tags", "Entries")
+    for _, it := range topItems(s.Tags, top) {
+        t.Row(it.Name, tools.FormatInt(it.Count))
+    }
+    sb.WriteString(t.Render() + "\n")
+
+    t = newStatsTable("Top PIDs", "Entries")
+    for _, it := range topItems(s.PIDs, top) {
+        t.Row(it.Name, tools.FormatInt(it.Count))
+    }
+    sb.WriteString(t.Render() + "\n")
+
+    if len(s.Errors) > 0 || len(s.Fatals) > 0 {
+        problems := map[string]int{}
+        for tag, n := range s.Errors {
+            problems[tag] += n
+        }
+        for tag, n := range s.Fatals {
+            problems[tag] += n
+        }
+
+        t = newStatsTable("Tags with errors", "Errors", "Fatal")
+        for _, it := range topItems(problems, top) {
+            t.Row(it.Name, tools.FormatInt(s.Errors[it.Name]), tools.FormatInt(s.Fatals[it.Name]))
+        }
+        sb.WriteString(t.Render() + "\n")
+    }
+
+    return sb.String()
+}
+
+// Gets the N items with the highest counts (all items when top <= 0)
+func topItems(counts map[string]int, top int) []statsItem {
+    items := []statsItem{}
+    for name, n := range counts {
+        items = append(items, statsItem{Name: name, Count: n})
+    }
+
+    sort.Slice(items, func(i, j int) bool {
+        if items[i].Count == items[j].Count {
+            return items[i].Name < items[j].Name
+        }
+        return items[i].Count > items[j].Count
+    })
+
+    if top > 0 && len(items) > top {
+        items = items[:top]
+    }
+
+    return items
+}
+
+func newStatsTable(headers ...string) *table.Table {
+    header := lipgloss.NewStyle().Bold(true).Padding(0, 1)
+    cell := lipgloss.NewStyle().Padding(0, 1)
+    number := cell.Align(lipgloss.Right)
+
+    return table.New().
+        Border(lipgloss.RoundedBorder()).
+        Headers(headers...).
+        StyleFunc(func(row, col int) lipgloss.Style {
+            switch {
+            case row == table.HeaderRow:
+                return header
+            case col > 0:
+                return number
+            default:
+                return cell
+            }
+        })
+}
@@ -0,0 +1,230 @@
+package readers
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/charmbracelet/lipgloss"
+    "github.com/charmbracelet/lipgloss/table"
+    "github.com/helviojunior/adbcat/internal/tools"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// TopView keeps the entries seen in the last seconds and ranks the busiest
+// tags, PIDs and packages, like 'top' does with the processes
+type TopView struct {
+    window time.Duration
+
+    mutex  sync.Mutex
+    events []topEvent
+    total  int
+    // The time of the first entry, the rates of the first seconds are divided by the
+    // time since then instead of the whole window
+    start time.Time
+}
+
+// The part of an entry needed by the view
+type topEvent struct {
+    at      time.Time
+    count   int
+    error   bool
+    tag     string
+    pid     string
+    pkg     string
+    message string
+}
+
+// One line of the top tables
+type topRow struct {
+    Name    string
+    Lines   int
+    Errors  int
+    Last    time.Time
+    Message string
+}
+
+// Creates a view of the entries seen within the window
+func NewTopView(window time.Duration) *TopView {
+    if window <= 0 {
+        window = 10 * time.Second
+    }
+
+    return &TopView{
+        window: window,
+        events: []topEvent{},
+    }
+}
+
+// Handles one entry, it is an EntryHandler
+func (t *TopView) Handle(entry *models.LogcatEntry) {
+    n := 1
+    if entry.Count > 1 {
+        n = entry.Count
+    }
+
+    // Only the first line of a multi-line entry (e.g. the stack traces)
+    msg, _, _ := strings.Cut(entry.Message, "\n")
+
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+
+    if t.start.IsZero() {
+        t.start = time.Now()
+    }
+    t.total += n
+    t.events = append(t.events, topEvent{
+        at:      time.Now(),
+        count:   n,
+        error:   models.LevelMap[entry.Level] >= models.LevelError,
+        tag:     strings.TrimSpace(entry.Tag),
+        pid:     entry.PID,
+        pkg:     entry.Package,
+        message: strings.TrimSpace(msg),
+    })
+}
+
+// Renders the tables with the top N tags, PIDs and packages
+func (t *TopView) Render(rows int, width int) string {
+    now := time.Now()
+    t.mutex.Lock()
+    t.expire(now)
+
+    tags := map[string]*topRow{}
+    pids := map[string]*topRow{}
+    pkgs := map[string]*topRow{}
+    lines := 0
+    for _, e := range t.events {
+        lines += e.count
+        topAdd(tags, e.tag, e)
+        topAdd(pids, e.pid, e)
+        if e.pkg != "" {
+            pids[e.pid].Name = e.pid + " " + e.pkg
+            topAdd(pkgs, e.pkg, e)
+        }
+    }
+    total := t.total
+    secs := t.seconds(now)
+    t.mutex.Unlock()
+
+    sb := strings.Builder{}
+    sb.WriteString(fmt.Sprintf(" Last %s: %.1f lines/s, %d tags, %d PIDs (%s entries since the start)\n",
+        t.window, float64(lines)/secs, len(tags), len(pids), tools.FormatInt(total)))
+
+    sb.WriteString(t.renderTable("Tag", tags, rows, width, secs) + "\n")
+    sb.WriteString(t.renderTable("PID", pids, rows, width, secs) + "\n")
+    if len(pkgs) > 0 {
+        sb.WriteString(t.renderTable("Package", pkgs, rows, width, secs) + "\n")
+    }
+
+    return sb.String()
+}
+
+// Drops the events older than the window, must be called with the mutex locked
+func (t *TopView) expire(now time.Time) {
+    i := 0
+    for i < len(t.events) && now.Sub(t.events[i].at) > t.window {
+        i++
+    }
+    if i > 0 {
+        t.events = append([]topEvent{}, t.events[i:]...)
+    }
+}
+
+// Gets the seconds the counters were taken in: the window, or less while it is still
+// filling. Must be called with the mutex locked.
+func (t *TopView) seconds(now time.Time) float64 {
+    elapsed := now.Sub(t.start)
+    if t.start.IsZero() || elapsed >= t.window {
+        return t.window.Seconds()
+    }
+    // Not the first instants, a single line would be thousands of lines per second
+    if elapsed < time.Second {
+        elapsed = time.Second
+    }
+    return elapsed.Seconds()
+}
+
+// Renders the table of the items, with the rates over the seconds
+func (t *TopView) renderTable(title string, items map[string]*topRow, rows int, width int, secs float64) string {
+    list := []*topRow{}
+    for _, r := range items {
+        list = append(list, r)
+    }
+
+    sort.Slice(list, func(i, j int) bool {
+        if list[i].Lines == list[j].Lines {
+            return list[i].Name < list[j].Name
+        }
+        return list[i].Lines > list[j].Lines
+    })
+
+    if rows > 0 && len(list) > rows {
+        list = list[:rows]
+    }
+
+    // Space left to the last message: the other columns, the borders and the padding
+    msgWidth := width - 30 - 10 - 9 - 9 - 16
+    if msgWidth < 10 {
+        msgWidth = 10
+    }
+
+    tbl := newStatsTable(title, "Lines/s", "Errors", "Error %", "Last message")
+    for _, r := range list {
+        rate := float64(r.Lines) / secs
+        errRate := 100 * float64(r.Errors) / float64(r.Lines)
+        tbl.Row(
+            topTrunc(r.Name, 30),
+            fmt.Sprintf("%.1f", rate),
+            tools.FormatInt(r.Errors),
+            fmt.Sprintf("%.0f%%", errRate),
+            topTrunc(r.Message, msgWidth),
+        )
+    }
+
+    // The last message is not a number, keep it aligned to the left
+    tbl.StyleFunc(func(row, col int) lipgloss.Style {
+        cell := lipgloss.NewStyle().Padding(0, 1)
+        switch {
+        case row == table.HeaderRow:
+            return cell.Bold(true)
+        case col > 0 && col < 4:
+            return cell.Align(lipgloss.Right)
+        default:
+            return cell
+        }
+    })
+
+    return tbl.Render()
+}
+
+func topAdd(items map[string]*topRow, name string, e topEvent) {
+    r, ok := items[name]
+    if !ok {
+        r = &topRow{Name: name}
+        items[name] = r
+    }
+
+    r.Lines += e.count
+    if e.error {
+        r.Errors += e.count
+    }
+    if !e.at.Before(r.Last) {
+        r.Last = e.at
+        r.Message = e.message
+    }
+}
+
+// Truncates a text to the size, in runes
+func topTrunc(text string, size int) string {
+    r := []rune(text)
+    if len(r) <= size {
+        return text
+    }
+    if size <= 3 {
+        return string(r[:size])
+    }
+    return string(r[:size-3]) + "..."
+}
@@ -0,0 +1,37 @@
+package readers
+
+import (
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// The rates of a window still filling are over the time since the first entry
+func TestTopViewRate(t *testing.T) {
+    tests := []struct {
+        name    string
+        elapsed time.Duration
+        want    string
+    }{
+        {"filling", 2 * time.Second, "2.0 lines/s"},
+        {"first instants", 100 * time.Millisecond, "4.0 lines/s"},
+        {"full window", time.Minute, "0.4 lines/s"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            view := NewTopView(10 * time.Second)
+            view.start = time.Now().Add(-tt.elapsed)
+            for i := 0; i < 4; i++ {
+                view.Handle(&models.LogcatEntry{Level: "I", Tag: "A", PID: "100", Message: "hello"})
+            }
+
+            got, _, _ := strings.Cut(view.Render(10, 120), "\n")
+            if !strings.Contains(got, tt.want) {
+                t.Errorf("got %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
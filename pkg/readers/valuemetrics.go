@@ -0,0 +1,250 @@
+package readers
+
+import (
+    "fmt"
+    "math"
+    "math/rand"
+    "regexp"
+    "slices"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/helviojunior/adbcat/internal/tools"
+    "github.com/helviojunior/adbcat/pkg/metrics"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+const (
+    // Values kept by rule for the percentiles of the summary, a random sample after that
+    valueMetricMaxSamples = 100000
+)
+
+var (
+    // The names of the metrics, they are exposed with the adbcat_ prefix
+    reMetricName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+    // The buckets of the histograms, fit for milliseconds
+    valueMetricBuckets = []float64{1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+)
+
+// ValueMetrics extracts numbers from the messages with the --metric rules, like
+// "display_ms=Displayed (?P<activity>\S+): \+(?P<value>\w+)". The value is the
+// group named value (or the first group), a number or a duration like 1s234ms
+// counted in milliseconds, and the other named groups are labels.
+// The values are histograms of the Prometheus endpoint and the summary at exit
+// has their percentiles.
+type ValueMetrics struct {
+    next  EntryHandler
+    rules []*valueRule
+
+    device func() string
+    mutex  sync.Mutex
+}
+
+type valueRule struct {
+    name   string
+    re     *regexp.Regexp
+    value  int      // The index of the value group
+    labels []string // The names of the label groups
+    groups []int    // The indexes of the label groups
+    caps   []*metrics.ValueCap
+
+    // nil without the Prometheus endpoint
+    histogram *metrics.HistogramVec
+
+    count  int
+    sum    float64
+    min    float64
+    max    float64
+    values []float64
+}
+
+// Creates the rules, like "name=regex". The histograms are added to the registry
+// when it is not nil, with the device label and at most maxLabels values by label.
+func NewValueMetrics(rules []string, registry *metrics.Registry, device func() string, maxLabels int, next EntryHandler) (*ValueMetrics, error) {
+    vm := &ValueMetrics{
+        next:   next,
+        rules:  []*valueRule{},
+        device: device,
+    }
+
+    names := map[string]bool{}
+    for _, r := range rules {
+        name, expr, ok := strings.Cut(r, "=")
+        name = strings.TrimSpace(name)
+        if !ok || !reMetricName.MatchString(name) {
+            return nil, fmt.Errorf("invalid metric '%s', use name=regex with a name like display_ms", r)
+        }
+        if names[name] {
+            return nil, fmt.Errorf("invalid metric '%s', the name %s is used by another metric rule", r, name)
+        }
+        if registry != nil && registry.HistogramClashes("adbcat_"+name) {
+            return nil, fmt.Errorf("invalid metric '%s', the name %s is used by a metric of adbcat", r, name)
+        }
+        names[name] = true
+
+        re, err := regexp.Compile(expr)
+        if err != nil {
+            return nil, fmt.Errorf("invalid metric regex '%s': %s", expr, err)
+        }
+        if re.NumSubexp() == 0 {
+            return nil, fmt.Errorf("invalid metric '%s', the regex needs a group with the value like (\\d+)", r)
+        }
+
+        rule := &valueRule{
+            name:   name,
+            re:     re,
+            value:  -1,
+            labels: []string{},
+            groups: []int{},
+            caps:   []*metrics.ValueCap{},
+            values: []float64{},
+        }
+        for i, g := range re.SubexpNames() {
+            switch {
+            case i == 0:
+            case g == "value":
+                rule.value = i
+            case g != "":
+                if !reMetricName.MatchString(g) || g == "device" || strings.HasPrefix(g, "__") {
+                    return nil, fmt.Errorf("invalid metric label '%s'", g)
+                }
+                rule.labels = append(rule.labels, g)
+                rule.groups = append(rule.groups, i)
+                rule.caps = append(rule.caps, metrics.NewValueCap(maxLabels))
+            }
+        }
+        if rule.value < 0 {
+            // The first group that is not a label
+            for i := 1; i <= re.NumSubexp() && rule.value < 0; i++ {
+                if !slices.Contains(rule.groups, i) {
+                    rule.value = i
+                }
+            }
+            if rule.value < 0 {
+                return nil, fmt.Errorf("invalid metric '%s', the regex needs a group with the value like (?P<value>\\d+)", r)
+            }
+        }
+
+        if registry != nil {
+            rule.histogram = registry.Histogram("adbcat_"+name,
+                fmt.Sprintf("Values of the metric rule %s.", name),
+                valueMetricBuckets, append([]string{"device"}, rule.labels...)...)
+        }
+
+        vm.rules = append(vm.rules, rule)
+    }
+
+    return vm, nil
+}
+
+// Handles one entry, it is an EntryHandler
+func (vm *ValueMetrics) Handle(entry *models.LogcatEntry) {
+    for _, r := range vm.rules {
+        m := r.re.FindStringSubmatch(entry.Message)
+        if m == nil {
+            continue
+        }
+
+        v, ok := parseMetricValue(m[r.value])
+        if !ok {
+            continue
+        }
+
+        vm.add(r, v)
+
+        if r.histogram != nil {
+            labels := []string{vm.device()}
+            for i, g := range r.groups {
+                labels = append(labels, r.caps[i].Value(m[g]))
+            }
+            r.histogram.Observe(v, labels...)
+        }
+    }
+
+    if vm.next != nil {
+        vm.next(entry)
+    }
+}
+
+// Keeps the value for the summary
+func (vm *ValueMetrics) add(r *valueRule, v float64) {
+    vm.mutex.Lock()
+    defer vm.mutex.Unlock()
+
+    if r.count == 0 || v < r.min {
+        r.min = v
+    }
+    if r.count == 0 || v > r.max {
+        r.max = v
+    }
+    r.count++
+    r.sum += v
+
+    // Reservoir sampling after the maximum
+    if len(r.values) < valueMetricMaxSamples {
+        r.values = append(r.values, v)
+    } else if i := rand.Intn(r.count); i < valueMetricMaxSamples {
+        r.values[i] = v
+    }
+}
+
+// Renders the summary of the rules: the count, the mean and the percentiles of the values
+func (vm *ValueMetrics) Summary() string {
+    vm.mutex.Lock()
+    defer vm.mutex.Unlock()
+
+    t := newStatsTable("Metric", "Count", "Min", "Mean", "p50", "p90", "p99", "Max")
+    for _, r := range vm.rules {
+        if r.count == 0 {
+            t.Row(r.name, "0", "-", "-", "-", "-", "-", "-")
+            continue
+        }
+
+        values := append([]float64{}, r.values...)
+        sort.Float64s(values)
+
+        t.Row(r.name,
+            tools.FormatInt(r.count),
+            formatMetricValue(r.min),
+            formatMetricValue(r.sum/float64(r.count)),
+            formatMetricValue(percentile(values, 50)),
+            formatMetricValue(percentile(values, 90)),
+            formatMetricValue(percentile(values, 99)),
+            formatMetricValue(r.max),
+        )
+    }
+
+    return t.Render() + "\n"
+}
+
+// Parses a value as a number, or as a duration in milliseconds like "1s234ms"
+func parseMetricValue(s string) (float64, bool) {
+    s = strings.TrimPrefix(strings.TrimSpace(s), "+")
+    if v, err := strconv.ParseFloat(s, 64); err == nil {
+        return v, true
+    }
+    if d, err := time.ParseDuration(s); err == nil {
+        return float64(d) / float64(time.Millisecond), true
+    }
+    return 0, false
+}
+
+// Gets the percentile p of the sorted values (nearest rank)
+func percentile(sorted []float64, p float64) float64 {
+    if len(sorted) == 0 {
+        return 0
+    }
+    i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+    return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+func formatMetricValue(v float64) string {
+    if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+        return strconv.FormatInt(int64(v), 10)
+    }
+    return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,88 @@
+package readers
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/helviojunior/adbcat/pkg/metrics"
+)
+
+func TestNewValueMetricsNames(t *testing.T) {
+    tests := []struct {
+        name  string
+        rules []string
+        err   string
+    }{
+        {"valid", []string{`display_ms=Displayed \S+: \+(\w+)`, `latency_ms=latency=(\d+)`}, ""},
+        {"invalid name", []string{`display-ms=(\d+)`}, "use name=regex"},
+        {"duplicate", []string{`x_ms=a=(\d+)`, `x_ms=b=(\d+)`}, "used by another metric rule"},
+        {"metric of adbcat", []string{`entries_total=(\d+)`}, "used by a metric of adbcat"},
+        {"series of a histogram", []string{`x=(\d+)`, `x_sum=(\d+)`}, "used by a metric of adbcat"},
+        {"histogram of a series", []string{`x_count=(\d+)`, `x=(\d+)`}, "used by a metric of adbcat"},
+        {"no group", []string{`x_ms=\d+`}, "needs a group"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            registry := metrics.NewRegistry()
+            registry.Counter("adbcat_entries_total", "Entries.", "device", "level")
+
+            _, err := NewValueMetrics(tt.rules, registry, func() string { return "" }, 10, nil)
+            if tt.err == "" {
+                if err != nil {
+                    t.Fatalf("unexpected error: %s", err)
+                }
+                return
+            }
+            if err == nil || !strings.Contains(err.Error(), tt.err) {
+                t.Errorf("got error %v, want %q", err, tt.err)
+            }
+        })
+    }
+}
+
+func TestPercentile(t *testing.T) {
+    sorted := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+    tests := []struct {
+        p    float64
+        want float64
+    }{
+        {0, 1},
+        {10, 1},
+        {50, 5},
+        {90, 9},
+        {99, 10},
+        {100, 10},
+    }
+
+    for _, tt := range tests {
+        if got := percentile(sorted, tt.p); got != tt.want {
+            t.Errorf("percentile(%g) = %g, want %g", tt.p, got, tt.want)
+        }
+    }
+    if got := percentile([]float64{}, 50); got != 0 {
+        t.Errorf("percentile of nothing = %g, want 0", got)
+    }
+}
+
+func TestParseMetricValue(t *testing.T) {
+    tests := []struct {
+        value string
+        want  float64
+        ok    bool
+    }{
+        {"320", 320, true},
+        {"+1.5", 1.5, true},
+        {"1s234ms", 1234, true},
+        {"250us", 0.25, true},
+        {"abc", 0, false},
+    }
+
+    for _, tt := range tests {
+        got, ok := parseMetricValue(tt.value)
+        if got != tt.want || ok != tt.ok {
+            t.Errorf("parseMetricValue(%q) = %g, %v, want %g, %v", tt.value, got, ok, tt.want, tt.ok)
+        }
+    }
+}
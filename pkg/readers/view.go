@@ -0,0 +1,171 @@
+package readers
+
+import (
+    "fmt"
+    "os"
+    "regexp"
+    "strings"
+
+    "github.com/helviojunior/adbcat/pkg/adb"
+    "github.com/helviojunior/adbcat/pkg/log"
+    "github.com/helviojunior/adbcat/pkg/models"
+    "github.com/helviojunior/adbcat/pkg/sinks"
+)
+
+var (
+    // The processes started and stopped by the ActivityManager, the package of the raw logcat lines
+    reStartProc = regexp.MustCompile(`^Start proc (\d+):([^/\s]+)`)
+    reProcDied  = regexp.MustCompile(`^Process (\S+) \(pid (\d+)\) has died`)
+)
+
+// FileViewer displays saved log files with the filters and the colors of the
+// logcat command, and may convert them to another log file format
+type FileViewer struct {
+    options  Options
+    minLevel string
+
+    dispatcher *sinks.Dispatcher
+
+    // The first stage that receives the entries, the last one is DispatchEntry
+    handler   EntryHandler
+    collapser *Collapser
+    values    *ValueMetrics
+
+    // The process names by PID, from the ActivityManager lines of the files without packages
+    processes map[string]string
+    // Entries with a package, read or resolved
+    packaged int
+}
+
+// Creates a viewer, the options are the ones of the logcat command
+func NewFileViewer(opts Options) (*FileViewer, error) {
+    var err error
+
+    viewer := &FileViewer{
+        options:   opts,
+        processes: map[string]string{},
+    }
+
+    if err := applyDisplayOptions(opts); err != nil {
+        return nil, err
+    }
+
+    viewer.minLevel = strings.ToUpper(opts.MinLevel)
+    if _, ok := models.LevelMap[viewer.minLevel]; !ok {
+        return nil, fmt.Errorf("invalid level '%s'", viewer.minLevel)
+    }
+
+    packages := []string{}
+    if opts.PackageName != "" {
+        packages = append(packages, opts.PackageName)
+    }
+    viewer.dispatcher, _, err = openOutputs(opts, func() *models.DeviceInfo { return nil }, packages)
+    if err != nil {
+        return nil, err
+    }
+
+    // Close the outputs when a stage fails
+    ready := false
+    defer func() {
+        if !ready {
+            if viewer.collapser != nil {
+                viewer.collapser.Close()
+            }
+            viewer.dispatcher.Close()
+        }
+    }()
+
+    // Chain the processing stages, from the last to the first
+    viewer.handler = viewer.DispatchEntry
+    if opts.Collapse {
+        viewer.collapser = NewCollapser(opts.CollapseWindow, opts.CollapseDigits, false, viewer.handler)
+        viewer.handler = viewer.collapser.Handle
+    }
+    if len(opts.ValueMetrics) > 0 {
+        viewer.values, err = NewValueMetrics(opts.ValueMetrics, nil, func() string { return "" }, 0, viewer.handler)
+        if err != nil {
+            return nil, err
+        }
+        viewer.handler = viewer.values.Handle
+    }
+    if extractFields(opts) || len(opts.Filters) > 0 {
+        fields, err := NewFieldExtractor(extractFields(opts), opts.Filters, viewer.handler)
+        if err != nil {
+            return nil, err
+        }
+        viewer.handler = fields.Handle
+    }
+
+    ready = true
+    return viewer, nil
+}
+
+// Displays the files in order. Returns the number of lines that could not be parsed.
+func (v *FileViewer) View(files []string) (int, error) {
+    defer v.dispatcher.Close()
+
+    unparsed := 0
+    for _, f := range files {
+        n, err := ReadLogFile(f, v.Handle)
+        if err != nil {
+            return unparsed, err
+        }
+        unparsed += n
+    }
+
+    if v.collapser != nil {
+        v.collapser.Close()
+    }
+
+    err := v.dispatcher.Close()
+    if v.options.PackageName != "" && v.packaged == 0 {
+        log.Warnf("No package in the files (nor ActivityManager 'Start proc' lines), -p matched nothing")
+    }
+    if v.values != nil {
+        fmt.Fprintf(os.Stderr, "\n%s", v.values.Summary())
+    }
+
+    return unparsed, err
+}
+
+// Checks the level, the package and the --include/--exclude terms, then sends the entry to the stages
+func (v *FileViewer) Handle(entry *models.LogcatEntry) {
+    v.resolvePackage(entry)
+
+    if !adb.IsLevelInScope(entry.Level, v.minLevel) {
+        return
+    }
+
+    if v.options.PackageName != "" && !strings.EqualFold(entry.Package, v.options.PackageName) {
+        return
+    }
+
+    if ignoreEntry(v.options, entry.PID, entry.Tag, entry.Message) {
+        return
+    }
+
+    v.handler(entry)
+}
+
+// Sets the package of the entries without one (the raw logcat and the adbcat text files) with
+// the processes started by the ActivityManager, as the live logcat does with 'adb shell ps'
+func (v *FileViewer) resolvePackage(entry *models.LogcatEntry) {
+    if strings.TrimSpace(entry.Tag) == "ActivityManager" {
+        if m := reStartProc.FindStringSubmatch(entry.Message); m != nil {
+            v.processes[m[1]] = m[2]
+        }else if m := reProcDied.FindStringSubmatch(entry.Message); m != nil && v.processes[m[2]] == m[1] {
+            delete(v.processes, m[2])
+        }
+    }
+
+    if entry.Package == "" {
+        entry.Package = v.processes[entry.PID]
+    }
+    if entry.Package != "" {
+        v.packaged++
+    }
+}
+
+func (v *FileViewer) DispatchEntry(entry *models.LogcatEntry) {
+    v.dispatcher.Handle(entry)
+}
@@ -0,0 +1,35 @@
+package readers
+
+import (
+    "testing"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+func TestResolvePackage(t *testing.T) {
+    v := &FileViewer{processes: map[string]string{}}
+
+    tests := []struct {
+        entry *models.LogcatEntry
+        want  string
+    }{
+        {&models.LogcatEntry{PID: "1234", Tag: "Acme", Message: "before the start"}, ""},
+        {&models.LogcatEntry{PID: "1000", Tag: "ActivityManager", Message: "Start proc 1234:com.acme.app/u0a123 for activity {com.acme.app/.Main}"}, ""},
+        {&models.LogcatEntry{PID: "1234", Tag: "Acme", Message: "started"}, "com.acme.app"},
+        {&models.LogcatEntry{PID: "1234", Tag: "Acme", Package: "com.other", Message: "package of the file"}, "com.other"},
+        {&models.LogcatEntry{PID: "1000", Tag: "ActivityManager", Message: "Start proc 1240:com.acme.app:remote/u0a123 for service"}, ""},
+        {&models.LogcatEntry{PID: "1240", Tag: "Remote", Message: "started"}, "com.acme.app:remote"},
+        {&models.LogcatEntry{PID: "1000", Tag: "ActivityManager", Message: "Process com.acme.app (pid 1234) has died: fg TOP"}, ""},
+        {&models.LogcatEntry{PID: "1234", Tag: "Other", Message: "PID reused"}, ""},
+    }
+
+    for _, tt := range tests {
+        v.resolvePackage(tt.entry)
+        if tt.entry.Package != tt.want {
+            t.Errorf("package of %q = %q, want %q", tt.entry.Message, tt.entry.Package, tt.want)
+        }
+    }
+    if v.packaged != 3 {
+        t.Errorf("packaged = %d, want 3", v.packaged)
+    }
+}
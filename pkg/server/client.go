@@ -0,0 +1,218 @@
+package server
+
+import (
+    "bufio"
+    "context"
+    "crypto/tls"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/log"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+const (
+    // Wait before reconnecting, doubled on each failure
+    clientMinBackoff = time.Second
+    clientMaxBackoff = 30 * time.Second
+)
+
+// StreamOptions selects the entries of a stream, the filters run in the server
+type StreamOptions struct {
+    Serial   string
+    Package  string
+    MinLevel string
+    // Expressions of the filter package, all of them must match
+    Filters []string
+    // Entries of the history sent first
+    Tail int
+}
+
+// Client reads the streams of a remote 'adbcat serve'
+type Client struct {
+    url   string
+    token string
+    http  *http.Client
+}
+
+// Creates a client of the server at target, like host:8080 or https://host:8443.
+// With insecure the certificate of the server is not checked.
+func NewClient(target string, token string, insecure bool) (*Client, error) {
+    if !strings.Contains(target, "://") {
+        target = "http://" + target
+    }
+    u, err := url.Parse(target)
+    if err != nil || u.Host == "" {
+        return nil, fmt.Errorf("invalid server '%s', use host:port or https://host:port", target)
+    }
+    if u.Scheme != "http" && u.Scheme != "https" {
+        return nil, fmt.Errorf("invalid server '%s', use http or https", target)
+    }
+
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    if insecure {
+        transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+    }
+
+    return &Client{
+        url:   strings.TrimRight(u.Scheme+"://"+u.Host+u.Path, "/"),
+        token: token,
+        http:  &http.Client{Transport: transport},
+    }, nil
+}
+
+// Gets the devices of the server
+func (c *Client) Devices(ctx context.Context) ([]*models.DeviceInfo, error) {
+    resp, err := c.get(ctx, "/api/devices", nil)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    devices := []*models.DeviceInfo{}
+    if err := json.NewDecoder(resp.Body).Decode(&devices); err != nil {
+        return nil, err
+    }
+    return devices, nil
+}
+
+// Reads the entries of the stream until ctx is done, reconnecting (from the last
+// entry seen) when the connection is lost. Returns the errors that a new attempt
+// would not fix, like an invalid token or filter.
+func (c *Client) Stream(ctx context.Context, options StreamOptions, handler func(*StreamEntry)) error {
+    query := url.Values{}
+    if options.Serial != "" {
+        query.Set("serial", options.Serial)
+    }
+    if options.Package != "" {
+        query.Set("package", options.Package)
+    }
+    if options.MinLevel != "" {
+        query.Set("level", options.MinLevel)
+    }
+    for _, f := range options.Filters {
+        query.Add("filter", f)
+    }
+    query.Set("tail", strconv.Itoa(options.Tail))
+
+    var lastId uint64
+    backoff := clientMinBackoff
+    connected := false
+
+    for ctx.Err() == nil {
+        if lastId > 0 {
+            query.Set("since", strconv.FormatUint(lastId, 10))
+        }
+
+        resp, err := c.get(ctx, "/stream", query)
+        if err == nil {
+            if connected {
+                log.Info("reconnected to the server")
+            }
+            connected = true
+            backoff = clientMinBackoff
+
+            lastId, err = readStream(resp.Body, lastId, handler)
+            resp.Body.Close()
+        }
+        if ctx.Err() != nil {
+            return nil
+        }
+        if _, ok := err.(permanentError); ok {
+            return err
+        }
+        log.Warn("connection to the server lost, retrying...", "err", err)
+
+        select {
+        case <-ctx.Done():
+            return nil
+        case <-time.After(backoff):
+        }
+        backoff = min(backoff*2, clientMaxBackoff)
+    }
+
+    return nil
+}
+
+// Reads the lines of a stream until it ends. Returns the id of the last entry.
+func readStream(body io.Reader, lastId uint64, handler func(*StreamEntry)) (uint64, error) {
+    scanner := bufio.NewScanner(body)
+    scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+    for scanner.Scan() {
+        line := scanner.Bytes()
+        if len(line) == 0 {
+            continue // keep-alive
+        }
+
+        ev := &StreamEntry{}
+        if err := json.Unmarshal(line, ev); err != nil {
+            log.Debug("invalid stream line", "err", err)
+            continue
+        }
+
+        if ev.LogcatEntry == nil || ev.Level == "" {
+            if ev.Dropped > 0 {
+                log.Warnf("%d entries dropped by the server, the connection is too slow", ev.Dropped)
+            }
+            continue
+        }
+
+        lastId = ev.ID
+        handler(ev)
+    }
+
+    err := scanner.Err()
+    if err == nil {
+        err = io.EOF
+    }
+    return lastId, err
+}
+
+// An error that a new attempt would not fix
+type permanentError struct {
+    msg string
+}
+
+func (e permanentError) Error() string {
+    return e.msg
+}
+
+// Sends a GET request with the token
+func (c *Client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+    u := c.url + path
+    if len(query) > 0 {
+        u += "?" + query.Encode()
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Authorization", "Bearer "+c.token)
+
+    resp, err := c.http.Do(req)
+    if err != nil {
+        return nil, err
+    }
+
+    switch {
+    case resp.StatusCode == http.StatusOK:
+        return resp, nil
+    case resp.StatusCode == http.StatusUnauthorized:
+        resp.Body.Close()
+        return nil, permanentError{"the server refused the token (--token or ADBCAT_TOKEN)"}
+    case resp.StatusCode >= 400 && resp.StatusCode < 500:
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+        resp.Body.Close()
+        return nil, permanentError{fmt.Sprintf("the server refused the request: %s", strings.TrimSpace(string(msg)))}
+    default:
+        resp.Body.Close()
+        return nil, fmt.Errorf("%s: %s", u, resp.Status)
+    }
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+    "sync"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+const (
+    // Entries queued for a client before they are dropped, for the slow clients
+    clientQueueSize = 4096
+)
+
+// Hub sends the entries of the devices to the connected clients and keeps the
+// last ones, so a new client (or one reconnecting) gets the recent history first
+type Hub struct {
+    mutex   sync.Mutex
+    size    int
+    history []*StreamEntry
+    next    uint64
+    clients map[*client]bool
+}
+
+// StreamEntry is an entry sent to the clients, with its sequence number and the
+// serial of its device. The lines without entry report the entries dropped for
+// a slow client.
+type StreamEntry struct {
+    ID     uint64 `json:"id,omitempty"`
+    Device string `json:"device,omitempty"`
+    *models.LogcatEntry
+    Dropped int `json:"dropped,omitempty"`
+}
+
+// A connected client, the entries are dropped when its queue is full
+type client struct {
+    queue   chan *StreamEntry
+    match   func(*StreamEntry) bool
+    mutex   sync.Mutex
+    dropped int
+}
+
+// Creates a hub keeping the last size entries
+func NewHub(size int) *Hub {
+    return &Hub{
+        size:    size,
+        history: []*StreamEntry{},
+        next:    1,
+        clients: map[*client]bool{},
+    }
+}
+
+// Gets the EntryHandler of the entries of a device
+func (h *Hub) Handler(device string) func(*models.LogcatEntry) {
+    return func(entry *models.LogcatEntry) {
+        h.add(device, entry)
+    }
+}
+
+func (h *Hub) add(device string, entry *models.LogcatEntry) {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    ev := &StreamEntry{ID: h.next, Device: device, LogcatEntry: entry}
+    h.next++
+
+    if h.size > 0 {
+        h.history = append(h.history, ev)
+        if len(h.history) > h.size {
+            h.history = h.history[len(h.history)-h.size:]
+        }
+    }
+
+    for c := range h.clients {
+        if c.match != nil && !c.match(ev) {
+            continue
+        }
+        select {
+        case c.queue <- ev:
+        default:
+            c.mutex.Lock()
+            c.dropped++
+            c.mutex.Unlock()
+        }
+    }
+}
+
+// Adds a client that gets the entries matching match (all when nil). It gets the
+// entries of the history after the id lastId first, or the last tail ones when
+// lastId is 0 (tail < 0 is all the history).
+func (h *Hub) subscribe(lastId uint64, tail int, match func(*StreamEntry) bool) *client {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    c := &client{
+        queue: make(chan *StreamEntry, clientQueueSize+h.size),
+        match: match,
+    }
+
+    history := []*StreamEntry{}
+    for _, ev := range h.history {
+        if ev.ID > lastId && (match == nil || match(ev)) {
+            history = append(history, ev)
+        }
+    }
+    if lastId == 0 && tail >= 0 && len(history) > tail {
+        history = history[len(history)-tail:]
+    }
+    for _, ev := range history {
+        c.queue <- ev
+    }
+    h.clients[c] = true
+
+    return c
+}
+
+func (h *Hub) unsubscribe(c *client) {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    delete(h.clients, c)
+}
+
+// Gets the number of connected clients
+func (h *Hub) Clients() int {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    return len(h.clients)
+}
+
+// Gets and resets the entries dropped since the last call
+func (c *client) takeDropped() int {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    n := c.dropped
+    c.dropped = 0
+    return n
+}
@@ -0,0 +1,323 @@
+package server
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "embed"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io/fs"
+    "net"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/filter"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+const (
+    // A comment (an empty line in the NDJSON streams) is sent to the idle streams
+    // so the proxies keep them open
+    keepAliveInterval = 15 * time.Second
+
+    // The cookie of the viewer with the token, set by /api/session
+    sessionCookie = "adbcat_token"
+)
+
+// The web viewer: the page, the script and the styles
+//
+//go:embed static
+var staticFiles embed.FS
+
+// ServerOptions configures the server
+type ServerOptions struct {
+    // The address to listen at, like :8080
+    Addr string
+    // The token the clients must send (Authorization: Bearer, ?token= or the cookie of the viewer)
+    Token string
+    // The certificate and the key for HTTPS (and HTTP/2), plain HTTP when empty
+    TLSCert string
+    TLSKey  string
+    // Gets the devices of the entries, shown by the viewer and the clients
+    Devices func() []*models.DeviceInfo
+}
+
+// Server serves the live viewer and the streams of the entries of a hub:
+//
+//    /            the web viewer
+//    /events      the entries as server-sent events, for the viewer
+//    /stream      the entries as NDJSON, selected by the filters of the query
+//    /api/devices the devices as JSON
+//    /api/session sets the cookie with the token, so the viewer does not keep it in the URL
+type Server struct {
+    options ServerOptions
+    hub     *Hub
+    http    *http.Server
+    addr    string
+
+    // The devices are read on the first request
+    devicesOnce sync.Once
+    devices     []*models.DeviceInfo
+}
+
+// Creates a random token for the clients
+func NewToken() string {
+    b := make([]byte, 16)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
+
+// Starts serving the hub. The address is checked before returning, the server
+// runs until it is closed.
+func Listen(options ServerOptions, hub *Hub) (*Server, error) {
+    if options.Token == "" {
+        return nil, fmt.Errorf("the server needs a token")
+    }
+    if (options.TLSCert == "") != (options.TLSKey == "") {
+        return nil, fmt.Errorf("HTTPS needs both the certificate and the key")
+    }
+
+    ln, err := net.Listen("tcp", options.Addr)
+    if err != nil {
+        return nil, fmt.Errorf("invalid HTTP address '%s': %s", options.Addr, err)
+    }
+
+    s := &Server{
+        options: options,
+        hub:     hub,
+        addr:    ln.Addr().String(),
+    }
+
+    static, err := fs.Sub(staticFiles, "static")
+    if err != nil {
+        ln.Close()
+        return nil, err
+    }
+
+    mux := http.NewServeMux()
+    mux.Handle("/", http.FileServer(http.FS(static)))
+    mux.HandleFunc("/events", s.authorize(s.serveEvents))
+    mux.HandleFunc("/stream", s.authorize(s.serveStream))
+    mux.HandleFunc("/api/devices", s.authorize(s.serveDevices))
+    mux.HandleFunc("/api/session", s.authorize(s.serveSession))
+
+    s.http = &http.Server{Handler: mux}
+    if options.TLSCert != "" {
+        go s.http.ServeTLS(ln, options.TLSCert, options.TLSKey)
+    }else{
+        go s.http.Serve(ln)
+    }
+
+    return s, nil
+}
+
+// Checks if the server uses HTTPS
+func (s *Server) TLS() bool {
+    return s.options.TLSCert != ""
+}
+
+// Gets the URL of the viewer, with the token. The viewer removes it from the
+// address bar when the page is loaded.
+func (s *Server) URL() string {
+    scheme := "http"
+    if s.options.TLSCert != "" {
+        scheme = "https"
+    }
+    return fmt.Sprintf("%s://%s/?token=%s", scheme, s.addr, s.options.Token)
+}
+
+// Stops the server, closing the streams
+func (s *Server) Close() error {
+    return s.http.Close()
+}
+
+// Checks the token of the request
+func (s *Server) authorize(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        token := r.URL.Query().Get("token")
+        if c, err := r.Cookie(sessionCookie); err == nil && token == "" {
+            token = c.Value
+        }
+        if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+            token = strings.TrimPrefix(auth, "Bearer ")
+        }
+
+        if subtle.ConstantTimeCompare([]byte(token), []byte(s.options.Token)) != 1 {
+            http.Error(w, "invalid token", http.StatusUnauthorized)
+            return
+        }
+
+        next(w, r)
+    }
+}
+
+// Sets the cookie with the token for the requests of the viewer (the event source
+// can not send headers), it lasts until the browser is closed
+func (s *Server) serveSession(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "use POST", http.StatusMethodNotAllowed)
+        return
+    }
+
+    http.SetCookie(w, &http.Cookie{
+        Name:     sessionCookie,
+        Value:    s.options.Token,
+        Path:     "/",
+        HttpOnly: true,
+        Secure:   s.TLS(),
+        SameSite: http.SameSiteStrictMode,
+    })
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// Streams the entries as server-sent events, the history first. A client
+// reconnecting with Last-Event-ID gets only the entries it did not see.
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+    lastId, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+
+    s.stream(w, r, s.hub.subscribe(lastId, -1, nil), "text/event-stream",
+        func(ev *StreamEntry) string {
+            data, _ := json.Marshal(ev)
+            return fmt.Sprintf("id: %d\ndata: %s\n\n", ev.ID, data)
+        },
+        func(dropped int) string {
+            return fmt.Sprintf("event: dropped\ndata: %d\n\n", dropped)
+        },
+        ": keep-alive\n\n",
+    )
+}
+
+// Streams the entries as NDJSON, one entry by line. The query selects the entries:
+//
+//    serial   the serial of the device
+//    package  the package of the entries
+//    level    the minimum level
+//    filter   an expression of the filter package, all of them must match (may repeat)
+//    since    the id of the last entry seen, to continue after a reconnection
+//    tail     the entries of the history sent first (default 0)
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request) {
+    query := r.URL.Query()
+
+    match, err := streamFilter(query.Get("serial"), query.Get("package"), query.Get("level"), query["filter"])
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    since, _ := strconv.ParseUint(query.Get("since"), 10, 64)
+    tail, _ := strconv.Atoi(query.Get("tail"))
+    if tail < 0 {
+        tail = 0
+    }
+
+    s.stream(w, r, s.hub.subscribe(since, tail, match), "application/x-ndjson",
+        func(ev *StreamEntry) string {
+            data, _ := json.Marshal(ev)
+            return string(data) + "\n"
+        },
+        func(dropped int) string {
+            data, _ := json.Marshal(&StreamEntry{Dropped: dropped})
+            return string(data) + "\n"
+        },
+        "\n",
+    )
+}
+
+// Writes the entries of a client until the request ends, the formats of the
+// entries, of the dropped entries and of the keep-alive are of the stream
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, c *client, contentType string,
+    entry func(*StreamEntry) string, dropped func(int) string, keepAlive string) {
+
+    defer s.hub.unsubscribe(c)
+
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        http.Error(w, "streaming not supported", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", contentType)
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("X-Accel-Buffering", "no")
+    w.WriteHeader(http.StatusOK)
+    flusher.Flush()
+
+    ticker := time.NewTicker(keepAliveInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-r.Context().Done():
+            return
+        case <-ticker.C:
+            fmt.Fprint(w, keepAlive)
+        case ev := <-c.queue:
+            fmt.Fprint(w, entry(ev))
+
+            // Send what is queued before flushing
+            for n := len(c.queue); n > 0; n-- {
+                fmt.Fprint(w, entry(<-c.queue))
+            }
+        }
+
+        if n := c.takeDropped(); n > 0 {
+            fmt.Fprint(w, dropped(n))
+        }
+        flusher.Flush()
+    }
+}
+
+// Sends the devices as JSON
+func (s *Server) serveDevices(w http.ResponseWriter, r *http.Request) {
+    s.devicesOnce.Do(func() {
+        s.devices = []*models.DeviceInfo{}
+        if s.options.Devices != nil {
+            s.devices = s.options.Devices()
+        }
+    })
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(s.devices)
+}
+
+// Creates the match function of the entries of a stream
+func streamFilter(serial string, pkg string, level string, expressions []string) (func(*StreamEntry) bool, error) {
+    minLevel := 0
+    if level != "" {
+        var ok bool
+        if minLevel, ok = models.LevelMap[strings.ToUpper(level)]; !ok {
+            return nil, fmt.Errorf("invalid level '%s'", level)
+        }
+    }
+
+    filters := []*filter.Filter{}
+    for _, e := range expressions {
+        f, err := filter.Parse(e)
+        if err != nil {
+            return nil, err
+        }
+        filters = append(filters, f)
+    }
+
+    return func(ev *StreamEntry) bool {
+        if serial != "" && ev.Device != serial {
+            return false
+        }
+        if pkg != "" && !strings.EqualFold(ev.Package, pkg) {
+            return false
+        }
+        if models.LevelMap[ev.Level] < minLevel {
+            return false
+        }
+        for _, f := range filters {
+            if !f.Match(ev.LogcatEntry) {
+                return false
+            }
+        }
+        return true
+    }, nil
+}
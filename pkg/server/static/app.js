@@ -0,0 +1,337 @@
+// The live viewer of adbcat: the entries come from /events (server-sent events),
+// the filter, the search and the pause run in the browser.
+(function () {
+    "use strict";
+
+    // Rows kept in the page, the oldest are removed
+    const maxRows = 10000;
+    const levels = ["V", "D", "I", "W", "E", "F"];
+    const tagColors = [
+        "#ed5565", "#8cc152", "#f6bb42", "#5d9cec", "#ac92ec", "#4fc1e9",
+        "#ff7b7b", "#a0d468", "#ffce54", "#7fb2f0", "#c3a8f5", "#76d7f0",
+    ];
+
+    const $ = (id) => document.getElementById(id);
+    const log = $("log");
+
+    // The token comes in the URL printed by adbcat serve. It is exchanged for a cookie
+    // and removed from the address bar, so it does not stay in the history.
+    const params = new URLSearchParams(location.search);
+    const token = params.get("token");
+    if (token !== null) {
+        params.delete("token");
+        const query = params.toString();
+        history.replaceState(null, "", location.pathname + (query ? "?" + query : "") + location.hash);
+    }
+
+    let paused = false;
+    let pending = [];
+    let filter = () => true;
+    let searchTerm = "";
+    let current = -1;
+    let total = 0;
+    let dropped = 0;
+
+    // The same tag always gets the same color
+    function tagColor(tag) {
+        let h = 0;
+        for (let i = 0; i < tag.length; i++) {
+            h = (h * 31 + tag.charCodeAt(i)) | 0;
+        }
+        return tagColors[Math.abs(h) % tagColors.length];
+    }
+
+    function span(cls, text) {
+        const s = document.createElement("span");
+        s.className = cls;
+        s.textContent = text;
+        return s;
+    }
+
+    function newRow(e) {
+        const row = document.createElement("div");
+        row.className = "row lvl-" + e.level;
+        row.entry = e;
+        row.title = e.device || "";
+
+        const tag = (e.tag || "").trim();
+        const tagSpan = span("tag", tag);
+        tagSpan.style.color = tagColor(tag);
+        tagSpan.title = tag + (e.package ? " (" + e.package + ")" : "");
+
+        let msg = e.message || "";
+        if (e.count > 1) {
+            msg += " (x" + e.count + ")";
+        }
+
+        row.append(
+            span("dev", e.device || ""),
+            span("time", e.date + " " + e.time),
+            span("pid", (e.pid || "").padStart(5) + " " + (e.tid || "").padStart(5)),
+            tagSpan,
+            span("lvl", e.level),
+            span("msg", msg),
+        );
+        row.classList.toggle("hidden", !filter(e));
+        if (searchTerm) {
+            highlight(row);
+        }
+        return row;
+    }
+
+    function atBottom() {
+        return log.scrollHeight - log.scrollTop - log.clientHeight < 40;
+    }
+
+    function add(entries) {
+        const follow = $("follow").checked && atBottom();
+        const frag = document.createDocumentFragment();
+        for (const e of entries) {
+            frag.append(newRow(e));
+        }
+        log.append(frag);
+
+        while (log.childElementCount > maxRows) {
+            log.firstElementChild.remove();
+        }
+        if (follow) {
+            log.scrollTop = log.scrollHeight;
+        }
+        updateCounts();
+    }
+
+    function updateCounts() {
+        let text = total + " entries";
+        if (paused) {
+            text += ", " + pending.length + " waiting";
+        }
+        if (dropped > 0) {
+            text += ", " + dropped + " dropped (the browser was too slow)";
+        }
+        $("counts").textContent = text;
+    }
+
+    // Builds the filter: the minimum level and a text like "tag:OkHttp pkg:com.acme error"
+    // or a /regex/, all the terms must match
+    function buildFilter() {
+        const min = levels.indexOf($("level").value);
+        const text = $("filter").value.trim();
+        const input = $("filter");
+        input.classList.remove("invalid");
+
+        const tests = [(e) => levels.indexOf(e.level) >= min];
+        const m = text.match(/^\/(.*)\/([a-z]*)$/);
+        if (m) {
+            try {
+                const re = new RegExp(m[1], m[2]);
+                tests.push((e) => re.test(e.tag + " " + e.message));
+            } catch (err) {
+                input.classList.add("invalid");
+            }
+        } else {
+            for (const term of text.split(/\s+/).filter(Boolean)) {
+                const lower = term.toLowerCase();
+                if (lower.startsWith("tag:")) {
+                    const v = lower.slice(4);
+                    tests.push((e) => (e.tag || "").trim().toLowerCase() === v);
+                } else if (lower.startsWith("pkg:")) {
+                    const v = lower.slice(4);
+                    tests.push((e) => (e.package || "").toLowerCase().startsWith(v));
+                } else if (lower.startsWith("dev:")) {
+                    const v = lower.slice(4);
+                    tests.push((e) => (e.device || "").toLowerCase().startsWith(v));
+                } else if (lower.startsWith("pid:")) {
+                    const v = lower.slice(4);
+                    tests.push((e) => e.pid === v);
+                } else {
+                    tests.push((e) => (e.tag + " " + e.message).toLowerCase().includes(lower));
+                }
+            }
+        }
+
+        filter = (e) => tests.every((t) => t(e));
+        for (const row of log.children) {
+            row.classList.toggle("hidden", !filter(row.entry));
+        }
+        if (atBottom()) {
+            log.scrollTop = log.scrollHeight;
+        }
+    }
+
+    // Marks the search term in the message of a row
+    function highlight(row) {
+        const msg = row.querySelector(".msg");
+        const text = msg.textContent;
+        msg.textContent = "";
+
+        if (!searchTerm) {
+            msg.textContent = text;
+            return;
+        }
+
+        const lower = text.toLowerCase();
+        let i = 0;
+        for (;;) {
+            const j = lower.indexOf(searchTerm, i);
+            if (j < 0) {
+                break;
+            }
+            msg.append(text.slice(i, j));
+            const mark = document.createElement("mark");
+            mark.textContent = text.slice(j, j + searchTerm.length);
+            msg.append(mark);
+            i = j + searchTerm.length;
+        }
+        msg.append(text.slice(i));
+    }
+
+    function search() {
+        searchTerm = $("search").value.toLowerCase();
+        current = -1;
+        for (const row of log.children) {
+            highlight(row);
+        }
+        const n = log.querySelectorAll(".row:not(.hidden) mark").length;
+        $("matches").textContent = searchTerm ? n + " matches" : "";
+    }
+
+    // Goes to the next (or the previous) match
+    function nextMatch(back) {
+        const marks = Array.from(log.querySelectorAll(".row:not(.hidden) mark"));
+        if (marks.length === 0) {
+            return;
+        }
+        if (current >= 0 && marks[current]) {
+            marks[current].classList.remove("current");
+        }
+        current = back ? current - 1 : current + 1;
+        current = (current + marks.length) % marks.length;
+
+        $("follow").checked = false;
+        marks[current].classList.add("current");
+        marks[current].scrollIntoView({ block: "center" });
+        $("matches").textContent = (current + 1) + "/" + marks.length + " matches";
+    }
+
+    function setPaused(p) {
+        paused = p;
+        $("pause").textContent = p ? "Resume" : "Pause";
+        $("pause").classList.toggle("active", p);
+        if (!p && pending.length > 0) {
+            const entries = pending;
+            pending = [];
+            add(entries);
+        }
+        updateCounts();
+    }
+
+    function connect() {
+        const source = new EventSource("events");
+        let batch = [];
+        let scheduled = false;
+
+        source.onopen = () => {
+            $("status").className = "status on";
+            $("notice").textContent = "";
+        };
+        source.onerror = () => {
+            $("status").className = "status off";
+            $("notice").textContent = "disconnected, retrying...";
+        };
+        source.onmessage = (msg) => {
+            total++;
+            batch.push(JSON.parse(msg.data));
+
+            // Render the entries once per frame
+            if (!scheduled) {
+                scheduled = true;
+                requestAnimationFrame(() => {
+                    scheduled = false;
+                    const entries = batch;
+                    batch = [];
+                    if (paused) {
+                        pending.push(...entries);
+                        updateCounts();
+                    } else {
+                        add(entries);
+                    }
+                });
+            }
+        };
+        source.addEventListener("dropped", (msg) => {
+            dropped += parseInt(msg.data, 10) || 0;
+            updateCounts();
+        });
+    }
+
+    function deviceName(d) {
+        let name = d.serial;
+        const model = [d.manufacturer, d.model].filter(Boolean).join(" ");
+        if (model) {
+            name = model + " - " + d.serial;
+        }
+        if (d.release) {
+            name += " (Android " + d.release + ")";
+        }
+        return name;
+    }
+
+    // Sets the cookie of the token, the requests without it use the cookie of an earlier load
+    function session() {
+        if (token === null) {
+            return Promise.resolve();
+        }
+        return fetch("api/session", {
+            method: "POST",
+            headers: { Authorization: "Bearer " + token },
+        });
+    }
+
+    session()
+        .then(() => fetch("api/devices"))
+        .then((r) => {
+            if (r.status === 401) {
+                throw new Error("invalid token, open the URL printed by adbcat serve");
+            }
+            return r.json();
+        })
+        .then((devices) => {
+            // The device column is shown with several devices
+            document.body.classList.toggle("multi", devices.length > 1);
+
+            let name = "unknown device";
+            if (devices.length === 1) {
+                name = deviceName(devices[0]);
+            } else if (devices.length > 1) {
+                name = devices.length + " devices";
+                $("device").title = devices.map(deviceName).join("\n");
+            }
+            $("device").textContent = name;
+            document.title = "adbcat - " + name;
+        })
+        .catch((err) => { $("device").textContent = err.message; })
+        .finally(connect);
+
+    $("level").addEventListener("change", buildFilter);
+    $("filter").addEventListener("input", buildFilter);
+    $("search").addEventListener("input", search);
+    $("search").addEventListener("keydown", (ev) => {
+        if (ev.key === "Enter") {
+            nextMatch(ev.shiftKey);
+        }
+    });
+    $("pause").addEventListener("click", () => setPaused(!paused));
+    $("clear").addEventListener("click", () => {
+        log.textContent = "";
+        pending = [];
+        total = 0;
+        updateCounts();
+    });
+    document.addEventListener("keydown", (ev) => {
+        if (ev.key === " " && ev.target === document.body) {
+            ev.preventDefault();
+            setPaused(!paused);
+        }
+    });
+
+})();
@@ -0,0 +1,153 @@
+package sinks
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "sync/atomic"
+    "text/template"
+
+    "github.com/helviojunior/adbcat/internal/tools"
+    "github.com/helviojunior/adbcat/pkg/log"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+const (
+    // Alerts waiting to be posted, the new ones are dropped when it is full
+    alertQueueSize = 100
+)
+
+var (
+    // The built-in templates of the alert body
+    AlertTemplates = map[string]string{
+        // The payload as JSON, for generic endpoints
+        "json": `{{json .}}`,
+        // Slack incoming webhooks (and the compatible ones: Mattermost, Rocket.Chat, Discord /slack)
+        "slack": `{"text": {{printf "*adbcat alert* `+"`%s`"+` on %s%s\n`+"```"+`\n%s\n`+"```"+`" .Rule .DeviceName .SuppressedText (join .Lines "\n") | json}}}`,
+    }
+)
+
+// AlertOptions configures the webhook of the alerts
+type AlertOptions struct {
+    // Where the alerts are posted
+    Webhook string
+    // The body of the requests: json, slack or a Go template over AlertPayload
+    Template string
+}
+
+// AlertPayload is the data of an alert, the body of the json template
+type AlertPayload struct {
+    Rule       string              `json:"rule"`
+    Time       string              `json:"time"`
+    Entry      *models.LogcatEntry `json:"entry"`
+    Device     *models.DeviceInfo  `json:"device,omitempty"`
+    DeviceName string              `json:"device_name"`
+    // The context as plain lines: the entries before, the triggering entry and the entries after
+    Lines []string `json:"lines"`
+    // Alerts of the rule skipped by the debounce since the last one
+    Suppressed     int    `json:"suppressed"`
+    SuppressedText string `json:"-"`
+}
+
+// AlertSink posts the alerts to a webhook. The rules are checked before (see
+// readers.Alerts), the alerts are queued and posted by a goroutine, so a slow
+// webhook does not hold the entries.
+type AlertSink struct {
+    endpoint *httpEndpoint
+    body     *template.Template
+
+    queue   chan *AlertPayload
+    done    chan bool
+    dropped atomic.Int64
+}
+
+// Creates the alert sink and starts posting
+func NewAlertSink(options AlertOptions) (*AlertSink, error) {
+    if options.Webhook == "" {
+        return nil, fmt.Errorf("the alert rules need a webhook URL (--alert-webhook)")
+    }
+
+    endpoint, err := newHttpEndpoint(options.Webhook, "")
+    if err != nil {
+        return nil, err
+    }
+
+    text := options.Template
+    if text == "" {
+        text = "json"
+    }
+    if t, ok := AlertTemplates[strings.ToLower(text)]; ok {
+        text = t
+    }
+
+    as := &AlertSink{
+        endpoint: endpoint,
+        queue:    make(chan *AlertPayload, alertQueueSize),
+        done:     make(chan bool),
+    }
+
+    as.body, err = template.New("alert").Funcs(template.FuncMap{
+        "json": func(v interface{}) (string, error) {
+            data, err := json.Marshal(v)
+            return string(data), err
+        },
+        "join": strings.Join,
+    }).Option("missingkey=zero").Parse(text)
+    if err != nil {
+        return nil, fmt.Errorf("invalid alert template: %s", err)
+    }
+
+    // Check the template with a sample alert
+    sample := &AlertPayload{
+        Rule:  "level==F",
+        Entry: &models.LogcatEntry{Level: "F", Tag: "Tag", PID: "1", TID: "1", Message: "message"},
+        Lines: []string{"line"},
+    }
+    if err := as.body.Execute(&bytes.Buffer{}, sample); err != nil {
+        return nil, fmt.Errorf("invalid alert template: %s", err)
+    }
+
+    go as.run()
+
+    return as, nil
+}
+
+// Queues an alert to be posted, it is dropped when the queue is full
+func (as *AlertSink) Send(payload *AlertPayload) {
+    select {
+    case as.queue <- payload:
+    default:
+        as.dropped.Add(1)
+    }
+}
+
+// Posts the queued alerts and stops
+func (as *AlertSink) Close() {
+    close(as.queue)
+    <-as.done
+
+    if dropped := as.dropped.Load(); dropped > 0 {
+        log.Warnf("%s alerts dropped (queue full)", tools.FormatInt64(dropped))
+    }
+}
+
+func (as *AlertSink) run() {
+    defer close(as.done)
+
+    for payload := range as.queue {
+        if err := as.post(payload); err != nil {
+            log.Warnf("Alert '%s' not sent: %s", payload.Rule, err)
+        }
+    }
+}
+
+func (as *AlertSink) post(payload *AlertPayload) error {
+    body := bytes.Buffer{}
+    if err := as.body.Execute(&body, payload); err != nil {
+        return err
+    }
+
+    _, err := as.endpoint.post("application/json", body.Bytes())
+    return err
+}
@@ -0,0 +1,274 @@
+package sinks
+
+import (
+    "errors"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/helviojunior/adbcat/internal/tools"
+    "github.com/helviojunior/adbcat/pkg/log"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+const (
+    // The errors of a sink are reported at most once in this interval
+    errorReportInterval = 10 * time.Second
+    // Close gives up on the sinks that did not finish in this time (e.g. a script
+    // that stopped reading), their entries are lost
+    closeTimeout = time.Minute
+)
+
+// Dispatcher sends every entry to all its sinks, each one through its own queue
+type Dispatcher struct {
+    mutex   sync.RWMutex
+    outputs []*output
+    closed  bool
+
+    // Closed by Close, it stops the queues and releases the entries waiting for them
+    stop chan bool
+}
+
+// SinkStats has the counters of a sink
+type SinkStats struct {
+    Name    string
+    Written int64
+    Dropped int64
+    Errors  int64
+}
+
+// A sink, its queue and its counters
+type output struct {
+    sink    Sink
+    options QueueOptions
+    queue   chan *models.LogcatEntry
+    stop    chan bool
+    done    chan error
+
+    written atomic.Int64
+    dropped atomic.Int64
+    errors  atomic.Int64
+
+    // Errors not reported yet, see reportError
+    lastReport time.Time
+    suppressed int64
+}
+
+func NewDispatcher() *Dispatcher {
+    return &Dispatcher{
+        outputs: []*output{},
+        stop:    make(chan bool),
+    }
+}
+
+// Adds a sink and starts its queue
+func (d *Dispatcher) Add(sink Sink, options QueueOptions) {
+    if options.Size <= 0 {
+        options.Size = DefaultQueueOptions().Size
+    }
+    if options.FlushInterval <= 0 {
+        options.FlushInterval = DefaultQueueOptions().FlushInterval
+    }
+
+    o := &output{
+        sink:    sink,
+        options: options,
+        queue:   make(chan *models.LogcatEntry, options.Size),
+        stop:    d.stop,
+        done:    make(chan error, 1),
+    }
+
+    d.mutex.Lock()
+    defer d.mutex.Unlock()
+
+    d.outputs = append(d.outputs, o)
+    go o.run()
+}
+
+// Queues the entry to all the sinks, it is an EntryHandler.
+// The entries received after Close are ignored.
+func (d *Dispatcher) Handle(entry *models.LogcatEntry) {
+    // The lock is not held while queuing, a full queue may block until Close
+    d.mutex.RLock()
+    outputs := d.outputs
+    closed := d.closed
+    d.mutex.RUnlock()
+
+    if closed {
+        return
+    }
+
+    for _, o := range outputs {
+        o.enqueue(entry)
+    }
+}
+
+// Gets the counters of the sinks
+func (d *Dispatcher) Stats() []SinkStats {
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    stats := []SinkStats{}
+    for _, o := range d.outputs {
+        stats = append(stats, SinkStats{
+            Name:    o.sink.Name(),
+            Written: o.written.Load(),
+            Dropped: o.dropped.Load(),
+            Errors:  o.errors.Load(),
+        })
+    }
+
+    return stats
+}
+
+// Writes the queued entries, flushes and closes the sinks.
+// Returns the errors of Close, it may be called more than once.
+func (d *Dispatcher) Close() error {
+    d.mutex.Lock()
+    if d.closed {
+        d.mutex.Unlock()
+        return nil
+    }
+    d.closed = true
+    close(d.stop)
+    outputs := d.outputs
+    d.mutex.Unlock()
+
+    errs := []error{}
+    deadline := time.Now().Add(closeTimeout)
+    for _, o := range outputs {
+        ok, err := o.wait(deadline)
+        if !ok {
+            log.Warnf("Sink '%s' did not finish in %s, its queued entries are lost", o.sink.Name(), closeTimeout)
+            continue
+        }
+        if err != nil {
+            errs = append(errs, err)
+        }
+
+        if dropped := o.dropped.Load(); dropped > 0 {
+            log.Warnf("Sink '%s' dropped %s entries (queue full)", o.sink.Name(), tools.FormatInt64(dropped))
+        }
+    }
+
+    return errors.Join(errs...)
+}
+
+// Queues an entry following the policy of the queue
+func (o *output) enqueue(entry *models.LogcatEntry) {
+    switch o.options.Policy {
+    case DropNewest:
+        select {
+        case o.queue <- entry:
+        default:
+            o.dropped.Add(1)
+        }
+
+    case DropOldest:
+        for {
+            select {
+            case o.queue <- entry:
+                return
+            default:
+            }
+
+            select {
+            case <-o.queue:
+                o.dropped.Add(1)
+            default:
+            }
+        }
+
+    default:
+        select {
+        case o.queue <- entry:
+        case <-o.stop:
+        }
+    }
+}
+
+// Writes the queued entries until the dispatcher is closed, then writes the
+// entries left in the queue, flushes and closes the sink
+func (o *output) run() {
+    flusher, _ := o.sink.(Flusher)
+    ticker := time.NewTicker(o.options.FlushInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case entry := <-o.queue:
+            o.write(entry)
+
+        case <-ticker.C:
+            if flusher != nil {
+                o.reportError(flusher.Flush())
+            }
+
+        case <-o.stop:
+            for len(o.queue) > 0 {
+                o.write(<-o.queue)
+            }
+            if flusher != nil {
+                o.reportError(flusher.Flush())
+            }
+            o.flushErrors()
+            o.done <- o.sink.Close()
+            return
+        }
+    }
+}
+
+// Waits for the sink to be closed until the deadline, returns false when the time
+// is over or the error of Close
+func (o *output) wait(deadline time.Time) (bool, error) {
+    select {
+    case err := <-o.done:
+        return true, err
+    default:
+    }
+
+    select {
+    case err := <-o.done:
+        return true, err
+    case <-time.After(time.Until(deadline)):
+        return false, nil
+    }
+}
+
+func (o *output) write(entry *models.LogcatEntry) {
+    if err := o.sink.Write(entry); err != nil {
+        o.reportError(err)
+        return
+    }
+    o.written.Add(1)
+}
+
+// Counts the error and logs it, the errors after the first one are summarized
+// every errorReportInterval so a broken sink does not flood the terminal
+func (o *output) reportError(err error) {
+    if err == nil {
+        return
+    }
+
+    o.errors.Add(1)
+    if time.Since(o.lastReport) < errorReportInterval {
+        o.suppressed++
+        return
+    }
+
+    if o.suppressed > 0 {
+        log.Warnf("Sink '%s': %s (and %s more errors)", o.sink.Name(), err, tools.FormatInt64(o.suppressed))
+    }else{
+        log.Warnf("Sink '%s': %s", o.sink.Name(), err)
+    }
+    o.lastReport = time.Now()
+    o.suppressed = 0
+}
+
+// Logs the number of errors not reported yet
+func (o *output) flushErrors() {
+    if o.suppressed > 0 {
+        log.Warnf("Sink '%s': %s more errors", o.sink.Name(), tools.FormatInt64(o.suppressed))
+        o.suppressed = 0
+    }
+}
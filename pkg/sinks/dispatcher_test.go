@@ -0,0 +1,76 @@
+package sinks
+
+import (
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// A sink that holds the writes until it is released, like a script that stopped reading
+type stalledSink struct {
+    release chan bool
+    written atomic.Int64
+}
+
+func (s *stalledSink) Name() string { return "stalled" }
+
+func (s *stalledSink) Write(entry *models.LogcatEntry) error {
+    <-s.release
+    s.written.Add(1)
+    return nil
+}
+
+func (s *stalledSink) Close() error { return nil }
+
+// Runs fn and fails when it does not return in time
+func returnsIn(t *testing.T, name string, fn func()) {
+    t.Helper()
+
+    done := make(chan bool)
+    go func() {
+        fn()
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-time.After(2 * time.Second):
+        t.Fatalf("%s blocked", name)
+    }
+}
+
+func TestDispatcherStalledSink(t *testing.T) {
+    sink := &stalledSink{release: make(chan bool)}
+    d := NewDispatcher()
+    d.Add(sink, QueueOptions{Size: 1, Policy: Block})
+
+    // The sink holds the first entry, the second fills the queue and the third blocks
+    handled := make(chan bool)
+    go func() {
+        for i := 0; i < 3; i++ {
+            d.Handle(&models.LogcatEntry{Message: "message"})
+        }
+        close(handled)
+    }()
+    time.Sleep(100 * time.Millisecond)
+
+    returnsIn(t, "Stats", func() { d.Stats() })
+
+    closed := make(chan error)
+    go func() { closed <- d.Close() }()
+
+    // Close releases the entry waiting for the queue
+    returnsIn(t, "Handle", func() { <-handled })
+    returnsIn(t, "Stats", func() { d.Stats() })
+
+    close(sink.release)
+    returnsIn(t, "Close", func() {
+        if err := <-closed; err != nil {
+            t.Errorf("unexpected error: %s", err)
+        }
+    })
+    if n := sink.written.Load(); n != 2 {
+        t.Errorf("written = %d, want the 2 entries queued before Close", n)
+    }
+}
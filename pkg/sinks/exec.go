@@ -0,0 +1,76 @@
+package sinks
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "os"
+    "os/exec"
+    "runtime"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// ExecSink runs a command (a script) and writes the entries to its standard input,
+// one JSON object per line. The output of the command goes to stderr, so it does
+// not mix with the entries of the terminal.
+type ExecSink struct {
+    command string
+    cmd     *exec.Cmd
+    stdin   io.WriteCloser
+    writer  *bufio.Writer
+}
+
+// Starts the command with the shell of the system (sh -c, or cmd /C on Windows)
+func NewExecSink(command string) (*ExecSink, error) {
+    var cmd *exec.Cmd
+    if runtime.GOOS == "windows" {
+        cmd = exec.Command("cmd", "/C", command)
+    }else{
+        cmd = exec.Command("sh", "-c", command)
+    }
+    cmd.Stdout = os.Stderr
+    cmd.Stderr = os.Stderr
+
+    stdin, err := cmd.StdinPipe()
+    if err != nil {
+        return nil, err
+    }
+    if err := cmd.Start(); err != nil {
+        return nil, fmt.Errorf("error starting '%s': %s", command, err)
+    }
+
+    return &ExecSink{
+        command: command,
+        cmd:     cmd,
+        stdin:   stdin,
+        writer:  bufio.NewWriter(stdin),
+    }, nil
+}
+
+func (xs *ExecSink) Name() string {
+    return "exec:" + xs.command
+}
+
+func (xs *ExecSink) Write(entry *models.LogcatEntry) error {
+    _, err := xs.writer.WriteString(entry.ToJson() + "\n")
+    return err
+}
+
+// Sends the buffered entries to the command, it is called every second by the Dispatcher
+func (xs *ExecSink) Flush() error {
+    return xs.writer.Flush()
+}
+
+// Closes the input of the command and waits for it to exit
+func (xs *ExecSink) Close() error {
+    err := xs.writer.Flush()
+    xs.stdin.Close()
+
+    // The exit status explains the errors of the writes
+    if werr := xs.cmd.Wait(); werr != nil {
+        return fmt.Errorf("'%s': %s", xs.command, werr)
+    }
+
+    return err
+}
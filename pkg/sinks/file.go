@@ -0,0 +1,137 @@
+package sinks
+
+import (
+    "fmt"
+    "os"
+    "strings"
+    "sync"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// The formats of the log file
+var FileFormats = []string{"text", "ansi", "json", "logcat"}
+
+// FileSink writes the entries to a log file in one of the FileFormats
+type FileSink struct {
+    path      string
+    file      *os.File
+    format    string
+    startSize int64
+    written   int64 // The bytes written, set by Close
+    studio    *models.StudioWriter
+
+    mutex sync.Mutex
+}
+
+// Checks the log file format, "" is text
+func CheckFileFormat(format string) error {
+    switch strings.ToLower(format) {
+    case "", "text", "ansi", "json", "logcat":
+        return nil
+    }
+
+    return fmt.Errorf("invalid log file format '%s', use %s", format, strings.Join(FileFormats, ", "))
+}
+
+// Opens a log file, the device (may return nil) and the packages go to the header of the .logcat files.
+// The text, ANSI and JSON files are appended, the .logcat files are replaced as they are one JSON object.
+func NewFileSink(path string, format string, device func() *models.DeviceInfo, packages []string) (*FileSink, error) {
+    if err := CheckFileFormat(format); err != nil {
+        return nil, err
+    }
+
+    fs := &FileSink{
+        path:   path,
+        format: strings.ToLower(format),
+    }
+
+    var err error
+    if fs.format == "logcat" {
+        fs.file, err = os.OpenFile(path, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0600)
+        if err != nil {
+            return nil, err
+        }
+        fs.studio, err = models.NewStudioWriter(fs.file, device(), packages)
+        if err != nil {
+            fs.file.Close()
+            return nil, err
+        }
+        return fs, nil
+    }
+
+    fs.file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+    if err != nil {
+        return nil, err
+    }
+    if fi, err := fs.file.Stat(); err == nil {
+        fs.startSize = fi.Size()
+    }
+
+    return fs, nil
+}
+
+func (fs *FileSink) Name() string {
+    return "file:" + fs.path
+}
+
+func (fs *FileSink) Write(entry *models.LogcatEntry) error {
+    fs.mutex.Lock()
+    defer fs.mutex.Unlock()
+
+    if fs.file == nil {
+        return fmt.Errorf("file closed")
+    }
+
+    switch fs.format {
+    case "ansi":
+        return entry.ToAnsiFile(fs.file)
+    case "json":
+        return entry.ToJsonFile(fs.file)
+    case "logcat":
+        return fs.studio.Write(entry)
+    default:
+        return entry.ToFile(fs.file)
+    }
+}
+
+// Gets the bytes written to the log file
+func (fs *FileSink) BytesWritten() int64 {
+    fs.mutex.Lock()
+    defer fs.mutex.Unlock()
+
+    return fs.bytesWritten()
+}
+
+func (fs *FileSink) bytesWritten() int64 {
+    if fs.file == nil {
+        return fs.written
+    }
+
+    if fi, err := fs.file.Stat(); err == nil {
+        return fi.Size() - fs.startSize
+    }
+    return 0
+}
+
+// Ends and closes the log file, it may be called more than once
+func (fs *FileSink) Close() error {
+    fs.mutex.Lock()
+    defer fs.mutex.Unlock()
+
+    if fs.file == nil {
+        return nil
+    }
+
+    var err error
+    if fs.studio != nil {
+        err = fs.studio.Close()
+    }
+    fs.written = fs.bytesWritten()
+    if cerr := fs.file.Close(); err == nil {
+        err = cerr
+    }
+    fs.file = nil
+
+    return err
+}
@@ -0,0 +1,109 @@
+package sinks
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "time"
+)
+
+const (
+    // Attempts of a request before the data is dropped
+    httpAttempts = 5
+    // Wait before the first retry, doubled on each retry
+    httpBackoff = 500 * time.Millisecond
+    httpTimeout = 30 * time.Second
+)
+
+// An endpoint of the HTTP sinks, the credentials of the URL are sent as basic auth
+type httpEndpoint struct {
+    url      string
+    user     string
+    password string
+    headers  map[string]string
+    client   *http.Client
+}
+
+// Parses the URL of an endpoint, path is added to the path of the URL (e.g. "/_bulk")
+func newHttpEndpoint(rawUrl string, path string) (*httpEndpoint, error) {
+    u, err := url.Parse(rawUrl)
+    if err != nil {
+        return nil, fmt.Errorf("invalid URL '%s': %s", rawUrl, err)
+    }
+    if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        return nil, fmt.Errorf("invalid URL '%s': use http(s)://host[:port][/path]", rawUrl)
+    }
+
+    ep := &httpEndpoint{
+        headers: map[string]string{},
+        client:  &http.Client{Timeout: httpTimeout},
+    }
+    if u.User != nil {
+        ep.user = u.User.Username()
+        ep.password, _ = u.User.Password()
+        u.User = nil
+    }
+    if path != "" {
+        u = u.JoinPath(path)
+    }
+    ep.url = u.String()
+
+    return ep, nil
+}
+
+// Posts the body, retrying the network errors, 429 and 5xx with exponential backoff.
+// Returns the body of the response.
+func (ep *httpEndpoint) post(contentType string, body []byte) ([]byte, error) {
+    var lastErr error
+    wait := httpBackoff
+
+    for attempt := 1; attempt <= httpAttempts; attempt++ {
+        if attempt > 1 {
+            time.Sleep(wait)
+            wait *= 2
+        }
+
+        req, err := http.NewRequest(http.MethodPost, ep.url, bytes.NewReader(body))
+        if err != nil {
+            return nil, err
+        }
+        req.Header.Set("Content-Type", contentType)
+        for k, v := range ep.headers {
+            req.Header.Set(k, v)
+        }
+        if ep.user != "" {
+            req.SetBasicAuth(ep.user, ep.password)
+        }
+
+        resp, err := ep.client.Do(req)
+        if err != nil {
+            lastErr = err
+            continue
+        }
+        data, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+        resp.Body.Close()
+
+        switch {
+        case resp.StatusCode >= 200 && resp.StatusCode < 300:
+            return data, err
+        case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+            lastErr = fmt.Errorf("%s: %s", resp.Status, shortBody(data))
+        default:
+            // The other errors (bad request, unauthorized...) will not change
+            return nil, fmt.Errorf("%s: %s", resp.Status, shortBody(data))
+        }
+    }
+
+    return nil, fmt.Errorf("%s (after %d attempts)", lastErr, httpAttempts)
+}
+
+// Gets the start of a response body for the error messages
+func shortBody(data []byte) string {
+    text := string(bytes.TrimSpace(data))
+    if len(text) > 200 {
+        text = text[:200] + "..."
+    }
+    return text
+}
@@ -0,0 +1,251 @@
+package sinks
+
+import (
+    "bytes"
+    "compress/gzip"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// The entry values that can be used as Loki labels
+var LokiLabelNames = []string{"device", "package", "level", "tag"}
+
+// LokiOptions configures the Grafana Loki exporter
+type LokiOptions struct {
+    // The URL of Loki, /loki/api/v1/push is added when the URL does not have it
+    URL string
+    // The entry values used as labels, from LokiLabelNames
+    Labels []string
+    // Fixed labels of all the streams, like job=adbcat
+    StaticLabels map[string]string
+    // The tenant of multi-tenant Loki (X-Scope-OrgID)
+    Tenant string
+    // Entries sent in one push
+    BatchSize int
+}
+
+// LokiSink sends the entries to the push API of Loki, grouped in streams by the
+// labels. The entries are sent in batches, gzip compressed, when BatchSize entries
+// are queued or when the dispatcher flushes the sink.
+type LokiSink struct {
+    options  LokiOptions
+    endpoint *httpEndpoint
+    device   *deviceCache
+
+    streams map[string]*lokiStream
+    count   int
+}
+
+type lokiStream struct {
+    Stream map[string]string `json:"stream"`
+    Values [][2]string       `json:"values"`
+
+    // The timestamps of the values, to sort them
+    times []int64
+}
+
+type lokiPush struct {
+    Streams []*lokiStream `json:"streams"`
+}
+
+// Checks the label names, the values must be from LokiLabelNames
+func CheckLokiLabels(labels []string) error {
+    for _, l := range labels {
+        ok := false
+        for _, n := range LokiLabelNames {
+            if strings.EqualFold(l, n) {
+                ok = true
+            }
+        }
+        if !ok {
+            return fmt.Errorf("invalid Loki label '%s', use %s", l, strings.Join(LokiLabelNames, ", "))
+        }
+    }
+    return nil
+}
+
+// Creates the exporter, the device (may return nil) is the device label
+func NewLokiSink(options LokiOptions, device func() *models.DeviceInfo) (*LokiSink, error) {
+    if err := CheckLokiLabels(options.Labels); err != nil {
+        return nil, err
+    }
+    if options.BatchSize <= 0 {
+        options.BatchSize = 1000
+    }
+    if options.StaticLabels == nil {
+        options.StaticLabels = map[string]string{}
+    }
+    if _, ok := options.StaticLabels["job"]; !ok {
+        options.StaticLabels["job"] = "adbcat"
+    }
+
+    endpoint, err := newHttpEndpoint(options.URL, "")
+    if err != nil {
+        return nil, err
+    }
+    if !strings.Contains(endpoint.url, "/loki/api/") {
+        endpoint.url = strings.TrimRight(endpoint.url, "/") + "/loki/api/v1/push"
+    }
+    endpoint.headers["Content-Encoding"] = "gzip"
+    if options.Tenant != "" {
+        endpoint.headers["X-Scope-OrgID"] = options.Tenant
+    }
+
+    return &LokiSink{
+        options:  options,
+        endpoint: endpoint,
+        device:   &deviceCache{fn: device},
+        streams:  map[string]*lokiStream{},
+    }, nil
+}
+
+func (ls *LokiSink) Name() string {
+    return "loki"
+}
+
+func (ls *LokiSink) Write(entry *models.LogcatEntry) error {
+    ts, err := entry.Timestamp()
+    if err != nil {
+        ts = time.Now()
+    }
+
+    labels := map[string]string{}
+    for k, v := range ls.options.StaticLabels {
+        labels[k] = v
+    }
+    for _, name := range ls.options.Labels {
+        var value string
+        switch strings.ToLower(name) {
+        case "device":
+            if d := ls.device.Get(); d != nil {
+                value = d.Serial
+            }
+        case "package":
+            value = entry.Package
+        case "level":
+            value = entry.Level
+        case "tag":
+            value = strings.TrimSpace(entry.Tag)
+        }
+        if value != "" {
+            labels[strings.ToLower(name)] = value
+        }
+    }
+
+    key := lokiStreamKey(labels)
+    stream, ok := ls.streams[key]
+    if !ok {
+        stream = &lokiStream{Stream: labels}
+        ls.streams[key] = stream
+    }
+
+    stream.Values = append(stream.Values, [2]string{strconv.FormatInt(ts.UnixNano(), 10), lokiLine(entry, labels)})
+    stream.times = append(stream.times, ts.UnixNano())
+    ls.count++
+
+    if ls.count >= ls.options.BatchSize {
+        return ls.Flush()
+    }
+    return nil
+}
+
+// Sends the queued entries, the values of every stream in timestamp order
+func (ls *LokiSink) Flush() error {
+    if ls.count == 0 {
+        return nil
+    }
+
+    push := lokiPush{Streams: []*lokiStream{}}
+    for _, stream := range ls.streams {
+        sort.Stable(byLokiTime{stream})
+        push.Streams = append(push.Streams, stream)
+    }
+    count := ls.count
+    ls.streams = map[string]*lokiStream{}
+    ls.count = 0
+
+    data, err := json.Marshal(push)
+    if err != nil {
+        return err
+    }
+
+    var body bytes.Buffer
+    zw := gzip.NewWriter(&body)
+    zw.Write(data)
+    if err := zw.Close(); err != nil {
+        return err
+    }
+
+    if _, err := ls.endpoint.post("application/json", body.Bytes()); err != nil {
+        return fmt.Errorf("%d entries dropped: %s", count, err)
+    }
+
+    return nil
+}
+
+func (ls *LokiSink) Close() error {
+    return nil
+}
+
+// Gets a key of the labels of a stream
+func lokiStreamKey(labels map[string]string) string {
+    keys := make([]string, 0, len(labels))
+    for k, v := range labels {
+        keys = append(keys, k+"="+strconv.Quote(v))
+    }
+    sort.Strings(keys)
+    return strings.Join(keys, ",")
+}
+
+// Formats the line of an entry as logfmt, without the values that are labels
+func lokiLine(entry *models.LogcatEntry, labels map[string]string) string {
+    sb := strings.Builder{}
+    add := func(key string, value string) {
+        if value == "" {
+            return
+        }
+        if _, ok := labels[key]; ok {
+            return
+        }
+        if strings.ContainsAny(value, " \t\r\n\"=") {
+            value = strconv.Quote(value)
+        }
+        sb.WriteString(key + "=" + value + " ")
+    }
+
+    add("level", entry.Level)
+    add("tag", strings.TrimSpace(entry.Tag))
+    add("pid", entry.PID)
+    add("tid", entry.TID)
+    add("package", entry.Package)
+    if entry.Count > 1 {
+        add("count", strconv.Itoa(entry.Count))
+    }
+    sb.WriteString("msg=" + strconv.Quote(entry.Message))
+
+    return sb.String()
+}
+
+// Sorts the values of a stream by timestamp
+type byLokiTime struct {
+    stream *lokiStream
+}
+
+func (b byLokiTime) Len() int {
+    return len(b.stream.Values)
+}
+
+func (b byLokiTime) Less(i, j int) bool {
+    return b.stream.times[i] < b.stream.times[j]
+}
+
+func (b byLokiTime) Swap(i, j int) {
+    b.stream.Values[i], b.stream.Values[j] = b.stream.Values[j], b.stream.Values[i]
+    b.stream.times[i], b.stream.times[j] = b.stream.times[j], b.stream.times[i]
+}
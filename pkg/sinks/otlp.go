@@ -0,0 +1,264 @@
+package sinks
+
+import (
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+var (
+    // The severity numbers of OpenTelemetry by logcat level
+    otlpSeverities = map[string]int{
+        "V": 1,  // TRACE
+        "D": 5,  // DEBUG
+        "I": 9,  // INFO
+        "W": 13, // WARN
+        "E": 17, // ERROR
+        "F": 21, // FATAL
+    }
+    otlpSeverityNames = map[string]string{
+        "V": "TRACE",
+        "D": "DEBUG",
+        "I": "INFO",
+        "W": "WARN",
+        "E": "ERROR",
+        "F": "FATAL",
+    }
+
+    // A W3C traceparent (version-traceid-spanid-flags)
+    reTraceparent = regexp.MustCompile(`\b[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}\b`)
+    // A trace id or span id logged as key=value (or "key": "value")
+    reTraceId = regexp.MustCompile(`(?i)\btrace[_.\-]?id"?\s*[=:]\s*"?([0-9a-f]{32})\b`)
+    reSpanId  = regexp.MustCompile(`(?i)\bspan[_.\-]?id"?\s*[=:]\s*"?([0-9a-f]{16})\b`)
+)
+
+// OtlpOptions configures the OpenTelemetry exporter
+type OtlpOptions struct {
+    // The URL of the collector, /v1/logs is added when the URL does not have it
+    Endpoint string
+    // Extra headers of the requests, like the authorization of a vendor
+    Headers map[string]string
+    // The service.name of the resource
+    ServiceName string
+    // Entries sent in one request
+    BatchSize int
+}
+
+// OtlpSink sends the entries as OpenTelemetry log records over OTLP/HTTP with the
+// JSON encoding. The device is the resource, the tag, PID, TID and package are
+// attributes of the records and the trace ids found in the messages link the
+// records to the traces.
+type OtlpSink struct {
+    options  OtlpOptions
+    endpoint *httpEndpoint
+    device   *deviceCache
+
+    records []otlpRecord
+}
+
+type otlpValue struct {
+    StringValue *string `json:"stringValue,omitempty"`
+    IntValue    *string `json:"intValue,omitempty"`
+    BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+type otlpAttribute struct {
+    Key   string    `json:"key"`
+    Value otlpValue `json:"value"`
+}
+
+type otlpRecord struct {
+    TimeUnixNano         string          `json:"timeUnixNano"`
+    ObservedTimeUnixNano string          `json:"observedTimeUnixNano"`
+    SeverityNumber       int             `json:"severityNumber"`
+    SeverityText         string          `json:"severityText"`
+    Body                 otlpValue       `json:"body"`
+    Attributes           []otlpAttribute `json:"attributes"`
+    TraceId              string          `json:"traceId,omitempty"`
+    SpanId               string          `json:"spanId,omitempty"`
+}
+
+type otlpRequest struct {
+    ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+    Resource struct {
+        Attributes []otlpAttribute `json:"attributes"`
+    } `json:"resource"`
+    ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpScopeLogs struct {
+    Scope struct {
+        Name string `json:"name"`
+    } `json:"scope"`
+    LogRecords []otlpRecord `json:"logRecords"`
+}
+
+// Creates the exporter, the device (may return nil) is the resource of the records
+func NewOtlpSink(options OtlpOptions, device func() *models.DeviceInfo) (*OtlpSink, error) {
+    if options.ServiceName == "" {
+        options.ServiceName = "adbcat"
+    }
+    if options.BatchSize <= 0 {
+        options.BatchSize = 512
+    }
+
+    endpoint, err := newHttpEndpoint(options.Endpoint, "")
+    if err != nil {
+        return nil, err
+    }
+    if !strings.HasSuffix(strings.TrimRight(endpoint.url, "/"), "/v1/logs") {
+        endpoint.url = strings.TrimRight(endpoint.url, "/") + "/v1/logs"
+    }
+    for k, v := range options.Headers {
+        endpoint.headers[k] = v
+    }
+
+    return &OtlpSink{
+        options:  options,
+        endpoint: endpoint,
+        device:   &deviceCache{fn: device},
+        records:  []otlpRecord{},
+    }, nil
+}
+
+func (ot *OtlpSink) Name() string {
+    return "otlp"
+}
+
+func (ot *OtlpSink) Write(entry *models.LogcatEntry) error {
+    ts, err := entry.Timestamp()
+    if err != nil {
+        ts = time.Now()
+    }
+
+    rec := otlpRecord{
+        TimeUnixNano:         strconv.FormatInt(ts.UnixNano(), 10),
+        ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+        SeverityNumber:       otlpSeverities[entry.Level],
+        SeverityText:         otlpSeverityNames[entry.Level],
+        Body:                 otlpString(entry.Message),
+        Attributes: []otlpAttribute{
+            {"android.log.tag", otlpString(strings.TrimSpace(entry.Tag))},
+        },
+    }
+    if pid, err := strconv.Atoi(entry.PID); err == nil {
+        rec.Attributes = append(rec.Attributes, otlpAttribute{"process.pid", otlpInt(int64(pid))})
+    }
+    if tid, err := strconv.Atoi(entry.TID); err == nil {
+        rec.Attributes = append(rec.Attributes, otlpAttribute{"thread.id", otlpInt(int64(tid))})
+    }
+    if entry.Package != "" {
+        rec.Attributes = append(rec.Attributes, otlpAttribute{"android.package", otlpString(entry.Package)})
+    }
+    if entry.Count > 1 {
+        rec.Attributes = append(rec.Attributes, otlpAttribute{"android.log.repeat_count", otlpInt(int64(entry.Count))})
+    }
+    rec.TraceId, rec.SpanId = findTraceIds(entry.Message)
+
+    ot.records = append(ot.records, rec)
+    if len(ot.records) >= ot.options.BatchSize {
+        return ot.Flush()
+    }
+    return nil
+}
+
+// Sends the queued records
+func (ot *OtlpSink) Flush() error {
+    if len(ot.records) == 0 {
+        return nil
+    }
+
+    scope := otlpScopeLogs{LogRecords: ot.records}
+    scope.Scope.Name = "adbcat"
+
+    res := otlpResourceLogs{ScopeLogs: []otlpScopeLogs{scope}}
+    res.Resource.Attributes = ot.resource()
+
+    count := len(ot.records)
+    ot.records = []otlpRecord{}
+
+    data, err := json.Marshal(otlpRequest{ResourceLogs: []otlpResourceLogs{res}})
+    if err != nil {
+        return err
+    }
+
+    if _, err := ot.endpoint.post("application/json", data); err != nil {
+        return fmt.Errorf("%d entries dropped: %s", count, err)
+    }
+
+    return nil
+}
+
+func (ot *OtlpSink) Close() error {
+    return nil
+}
+
+// Gets the attributes of the resource: the service and the device
+func (ot *OtlpSink) resource() []otlpAttribute {
+    attrs := []otlpAttribute{
+        {"service.name", otlpString(ot.options.ServiceName)},
+        {"os.type", otlpString("linux")},
+        {"os.name", otlpString("Android")},
+    }
+
+    d := ot.device.Get()
+    if d == nil {
+        return attrs
+    }
+
+    add := func(key string, value string) {
+        if value != "" {
+            attrs = append(attrs, otlpAttribute{key, otlpString(value)})
+        }
+    }
+    add("device.id", d.Serial)
+    add("device.manufacturer", d.Manufacturer)
+    add("device.model.identifier", d.Model)
+    add("device.model.name", d.Name())
+    add("os.version", d.Release)
+    add("os.build_id", d.BuildID)
+    if d.SDK > 0 {
+        attrs = append(attrs, otlpAttribute{"android.os.api_level", otlpString(strconv.Itoa(d.SDK))})
+    }
+    if d.Emulator {
+        emulator := true
+        attrs = append(attrs, otlpAttribute{"android.emulator", otlpValue{BoolValue: &emulator}})
+    }
+
+    return attrs
+}
+
+// Finds the trace and span ids in a message, as a W3C traceparent or as key=value
+func findTraceIds(message string) (string, string) {
+    if m := reTraceparent.FindStringSubmatch(message); m != nil {
+        return m[1], m[2]
+    }
+
+    traceId, spanId := "", ""
+    if m := reTraceId.FindStringSubmatch(message); m != nil {
+        traceId = strings.ToLower(m[1])
+    }
+    if m := reSpanId.FindStringSubmatch(message); m != nil && traceId != "" {
+        spanId = strings.ToLower(m[1])
+    }
+
+    return traceId, spanId
+}
+
+func otlpString(s string) otlpValue {
+    return otlpValue{StringValue: &s}
+}
+
+// The 64 bits integers are strings in the JSON encoding of OTLP
+func otlpInt(n int64) otlpValue {
+    s := strconv.FormatInt(n, 10)
+    return otlpValue{IntValue: &s}
+}
@@ -0,0 +1,154 @@
+package sinks
+
+import (
+    "fmt"
+    "slices"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// Sink is a destination of the entries (the terminal, a file, a network exporter...).
+//
+// Every sink has its own queue and goroutine in the Dispatcher, so Write is never
+// called concurrently and a slow sink does not hold the others. The entries are
+// shared between the sinks and must not be changed.
+type Sink interface {
+    // A short name for the messages, like "terminal" or "file:/tmp/log.txt"
+    Name() string
+    // Writes one entry
+    Write(entry *models.LogcatEntry) error
+    // Writes the pending data and releases the sink
+    Close() error
+}
+
+// Flusher is implemented by the sinks that buffer the entries (e.g. in batches).
+// Flush is called periodically and before Close, it should return soon when
+// there is nothing to send.
+type Flusher interface {
+    Flush() error
+}
+
+// Policy is what happens to a new entry when the queue of a sink is full
+type Policy int
+
+const (
+    // Block waits for the sink, nothing is lost but the other sinks wait as well
+    Block Policy = iota
+    // DropNewest discards the new entry
+    DropNewest
+    // DropOldest discards the oldest queued entry to make room for the new one
+    DropOldest
+)
+
+var policyNames = map[Policy]string{
+    Block:      "block",
+    DropNewest: "drop-newest",
+    DropOldest: "drop-oldest",
+}
+
+func (p Policy) String() string {
+    return policyNames[p]
+}
+
+// Parses a policy name: block, drop-newest or drop-oldest
+func ParsePolicy(name string) (Policy, error) {
+    for p, n := range policyNames {
+        if strings.EqualFold(name, n) {
+            return p, nil
+        }
+    }
+
+    return Block, fmt.Errorf("invalid queue policy '%s', use block, drop-newest or drop-oldest", name)
+}
+
+// The kinds of the sinks, the start of their names (before ':')
+var SinkKinds = []string{"terminal", "file", "elasticsearch", "loki", "otlp", "syslog", "exec"}
+
+// Gets the kind of a sink from its name, like "file" for "file:/tmp/log.txt"
+func SinkKind(name string) string {
+    kind, _, _ := strings.Cut(name, ":")
+    return kind
+}
+
+// Parses the queue policy rules like "loki=block" (the policy of a kind of sink)
+// or "drop-newest" (the policy of all the sinks). The key of the last one is "".
+func ParsePolicyRules(rules []string) (map[string]Policy, error) {
+    policies := map[string]Policy{}
+    for _, r := range rules {
+        kind, name, ok := strings.Cut(r, "=")
+        if !ok {
+            kind, name = "", r
+        }
+        kind = strings.ToLower(strings.TrimSpace(kind))
+        if kind != "" && !slices.Contains(SinkKinds, kind) {
+            return nil, fmt.Errorf("invalid sink '%s' in the queue policy '%s', use %s", kind, r, strings.Join(SinkKinds, ", "))
+        }
+
+        p, err := ParsePolicy(strings.TrimSpace(name))
+        if err != nil {
+            return nil, err
+        }
+        policies[kind] = p
+    }
+
+    return policies, nil
+}
+
+// QueueOptions sets how the entries are queued for a sink
+type QueueOptions struct {
+    // Number of entries waiting for the sink
+    Size int
+    // What to do when the queue is full
+    Policy Policy
+    // How often a Flusher is flushed
+    FlushInterval time.Duration
+}
+
+// The queue options of the local sinks (terminal and files): nothing is dropped
+func DefaultQueueOptions() QueueOptions {
+    return QueueOptions{
+        Size:          1024,
+        Policy:        Block,
+        FlushInterval: time.Second,
+    }
+}
+
+// The queue options of the exporters: a slow or down server drops the oldest entries
+// instead of holding the terminal
+func ExporterQueueOptions(flushInterval time.Duration) QueueOptions {
+    return QueueOptions{
+        Size:          10000,
+        Policy:        DropOldest,
+        FlushInterval: flushInterval,
+    }
+}
+
+// A device that could not be read is read again after this time
+const deviceRetryInterval = 10 * time.Second
+
+// deviceCache reads the device on the first use, when adb is connected, and
+// keeps it once it is read
+type deviceCache struct {
+    fn    func() *models.DeviceInfo
+    mutex sync.Mutex
+    info  *models.DeviceInfo
+    last  time.Time
+}
+
+// Gets the device, nil when unknown
+func (dc *deviceCache) Get() *models.DeviceInfo {
+    dc.mutex.Lock()
+    defer dc.mutex.Unlock()
+
+    if dc.info != nil || dc.fn == nil || time.Since(dc.last) < deviceRetryInterval {
+        return dc.info
+    }
+    dc.last = time.Now()
+    if d := dc.fn(); d != nil && d.Serial != "" {
+        dc.info = d
+    }
+    return dc.info
+}
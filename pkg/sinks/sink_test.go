@@ -0,0 +1,37 @@
+package sinks
+
+import (
+    "testing"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// A device that could not be read is read again, and kept once it is read
+func TestDeviceCacheRetry(t *testing.T) {
+    calls := 0
+    var device *models.DeviceInfo
+    dc := &deviceCache{fn: func() *models.DeviceInfo {
+        calls++
+        return device
+    }}
+
+    if d := dc.Get(); d != nil {
+        t.Fatalf("got %v, want nil", d)
+    }
+    if dc.Get(); calls != 1 {
+        t.Fatalf("read %d times before the retry interval, want 1", calls)
+    }
+
+    device = &models.DeviceInfo{Serial: "emulator-5554"}
+    dc.last = time.Now().Add(-deviceRetryInterval)
+    if d := dc.Get(); d == nil || d.Serial != "emulator-5554" {
+        t.Fatalf("got %v after the retry interval, want the device", d)
+    }
+
+    device = nil
+    dc.last = time.Now().Add(-deviceRetryInterval)
+    if d := dc.Get(); d == nil || calls != 2 {
+        t.Errorf("got %v after %d reads, want the kept device after 2", d, calls)
+    }
+}
@@ -0,0 +1,261 @@
+package sinks
+
+import (
+    "crypto/tls"
+    "fmt"
+    "net"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+const (
+    // The messages sent over UDP are cut at this size
+    syslogMaxDatagram = 8192
+    // Wait before reconnecting, doubled on each failure
+    syslogMinBackoff = time.Second
+    syslogMaxBackoff = 30 * time.Second
+    syslogDialTimeout = 5 * time.Second
+)
+
+var (
+    // The syslog severities by logcat level
+    syslogSeverities = map[string]int{
+        "V": 7, // debug
+        "D": 7, // debug
+        "I": 6, // informational
+        "W": 4, // warning
+        "E": 3, // error
+        "F": 2, // critical
+    }
+
+    // The syslog facilities by name
+    SyslogFacilities = map[string]int{
+        "kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+        "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+        "local0": 16, "local1": 17, "local2": 18, "local3": 19,
+        "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+    }
+)
+
+// SyslogOptions configures the syslog forwarder
+type SyslogOptions struct {
+    // The collector, like udp://host:514, tcp://host:601 or tls://host:6514
+    URL string
+    // The facility name, see SyslogFacilities
+    Facility string
+    // Do not check the certificate of the collector (tls)
+    Insecure bool
+    // Messages kept while the collector is unavailable
+    BufferSize int
+}
+
+// SyslogSink forwards the entries as RFC 5424 messages over UDP, TCP or TLS
+// (RFC 6587 octet-counting framing). The device serial is the HOSTNAME, the tag
+// the APP-NAME and the PID the PROCID. While the collector is unavailable the
+// messages are kept in a bounded buffer and the connection is retried with backoff.
+type SyslogSink struct {
+    options  SyslogOptions
+    network  string // udp, tcp or tls
+    address  string
+    facility int
+    device   *deviceCache
+
+    conn      net.Conn
+    pending   [][]byte
+    dropped   int
+    backoff   time.Duration
+    nextRetry time.Time
+}
+
+// Creates the forwarder, the device (may return nil) is the HOSTNAME of the messages.
+// The connection is opened on the first message.
+func NewSyslogSink(options SyslogOptions, device func() *models.DeviceInfo) (*SyslogSink, error) {
+    u, err := url.Parse(options.URL)
+    if err != nil || u.Host == "" {
+        return nil, fmt.Errorf("invalid syslog URL '%s', use udp://host:514, tcp://host:601 or tls://host:6514", options.URL)
+    }
+
+    ss := &SyslogSink{
+        options: options,
+        network: strings.ToLower(u.Scheme),
+        address: u.Host,
+        device:  &deviceCache{fn: device},
+        pending: [][]byte{},
+        backoff: syslogMinBackoff,
+    }
+
+    defaultPort := map[string]string{"udp": "514", "tcp": "601", "tls": "6514"}
+    port, ok := defaultPort[ss.network]
+    if !ok {
+        return nil, fmt.Errorf("invalid syslog protocol '%s', use udp, tcp or tls", u.Scheme)
+    }
+    if u.Port() == "" {
+        ss.address = net.JoinHostPort(u.Hostname(), port)
+    }
+
+    if options.Facility == "" {
+        options.Facility = "local0"
+    }
+    if ss.facility, ok = SyslogFacilities[strings.ToLower(options.Facility)]; !ok {
+        return nil, fmt.Errorf("invalid syslog facility '%s'", options.Facility)
+    }
+
+    if ss.options.BufferSize <= 0 {
+        ss.options.BufferSize = 10000
+    }
+
+    return ss, nil
+}
+
+func (ss *SyslogSink) Name() string {
+    return "syslog"
+}
+
+func (ss *SyslogSink) Write(entry *models.LogcatEntry) error {
+    if len(ss.pending) >= ss.options.BufferSize {
+        ss.pending = ss.pending[1:]
+        ss.dropped++
+    }
+    ss.pending = append(ss.pending, ss.format(entry))
+
+    return ss.send()
+}
+
+// Sends the buffered messages, if the collector is back
+func (ss *SyslogSink) Flush() error {
+    if len(ss.pending) == 0 {
+        return nil
+    }
+    return ss.send()
+}
+
+func (ss *SyslogSink) Close() error {
+    err := ss.Flush()
+
+    if ss.conn != nil {
+        ss.conn.Close()
+        ss.conn = nil
+    }
+
+    if lost := len(ss.pending) + ss.dropped; lost > 0 {
+        return fmt.Errorf("syslog: %d messages not sent, the collector is unavailable", lost)
+    }
+    return err
+}
+
+// Sends the buffered messages in order, connecting when needed.
+// On failure the messages stay in the buffer until the next retry.
+func (ss *SyslogSink) send() error {
+    if ss.conn == nil {
+        if time.Now().Before(ss.nextRetry) {
+            return nil
+        }
+        if err := ss.connect(); err != nil {
+            ss.nextRetry = time.Now().Add(ss.backoff)
+            ss.backoff = min(ss.backoff*2, syslogMaxBackoff)
+            return err
+        }
+    }
+
+    for len(ss.pending) > 0 {
+        msg := ss.pending[0]
+        if ss.network == "udp" {
+            if len(msg) > syslogMaxDatagram {
+                msg = msg[:syslogMaxDatagram]
+            }
+        }else{
+            // Octet-counting framing (RFC 6587)
+            msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+        }
+
+        ss.conn.SetWriteDeadline(time.Now().Add(syslogDialTimeout))
+        if _, err := ss.conn.Write(msg); err != nil {
+            ss.conn.Close()
+            ss.conn = nil
+            ss.nextRetry = time.Now().Add(ss.backoff)
+            ss.backoff = min(ss.backoff*2, syslogMaxBackoff)
+            return err
+        }
+        ss.pending = ss.pending[1:]
+    }
+
+    if ss.dropped > 0 {
+        dropped := ss.dropped
+        ss.dropped = 0
+        return fmt.Errorf("%d messages dropped while the collector was unavailable", dropped)
+    }
+
+    return nil
+}
+
+func (ss *SyslogSink) connect() error {
+    var err error
+    dialer := &net.Dialer{Timeout: syslogDialTimeout}
+
+    switch ss.network {
+    case "tls":
+        ss.conn, err = tls.DialWithDialer(dialer, "tcp", ss.address, &tls.Config{
+            InsecureSkipVerify: ss.options.Insecure,
+        })
+    default:
+        ss.conn, err = dialer.Dial(ss.network, ss.address)
+    }
+    if err != nil {
+        ss.conn = nil
+        return err
+    }
+
+    ss.backoff = syslogMinBackoff
+    return nil
+}
+
+// Formats an entry as a RFC 5424 message:
+//
+//    <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (ss *SyslogSink) format(entry *models.LogcatEntry) []byte {
+    ts, err := entry.Timestamp()
+    if err != nil {
+        ts = time.Now()
+    }
+
+    severity, ok := syslogSeverities[entry.Level]
+    if !ok {
+        severity = 7
+    }
+
+    hostname := "-"
+    if d := ss.device.Get(); d != nil {
+        hostname = syslogField(d.Serial, 255)
+    }
+
+    // The message is UTF-8, marked with a BOM
+    msg := entry.Message + entry.RepeatSuffix()
+
+    return []byte(fmt.Sprintf("<%d>1 %s %s %s %s - - \ufeff%s",
+        ss.facility*8+severity,
+        ts.Format("2006-01-02T15:04:05.000000Z07:00"),
+        hostname,
+        syslogField(strings.TrimSpace(entry.Tag), 48),
+        syslogField(entry.PID, 128),
+        msg,
+    ))
+}
+
+// Makes a header field: printable ASCII without spaces, cut at max, "-" when empty
+func syslogField(value string, max int) string {
+    b := []byte{}
+    for i := 0; i < len(value) && len(b) < max; i++ {
+        if value[i] > 32 && value[i] < 127 {
+            b = append(b, value[i])
+        }
+    }
+
+    if len(b) == 0 {
+        return "-"
+    }
+    return string(b)
+}
@@ -0,0 +1,37 @@
+package sinks
+
+import (
+    "fmt"
+    "io"
+
+    "github.com/fatih/color"
+    "github.com/helviojunior/adbcat/pkg/models"
+)
+
+// TerminalSink prints the entries with colors, cut (or wrapped) at the console width
+type TerminalSink struct {
+    out      io.Writer
+    showTime bool
+    showPid  bool
+}
+
+func NewTerminalSink(showTime bool, showPid bool) *TerminalSink {
+    return &TerminalSink{
+        out:      color.Output,
+        showTime: showTime,
+        showPid:  showPid,
+    }
+}
+
+func (ts *TerminalSink) Name() string {
+    return "terminal"
+}
+
+func (ts *TerminalSink) Write(entry *models.LogcatEntry) error {
+    _, err := fmt.Fprintln(ts.out, entry.FormatAnsiString(ts.showTime, ts.showPid, true))
+    return err
+}
+
+func (ts *TerminalSink) Close() error {
+    return nil
+}